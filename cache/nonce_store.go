@@ -0,0 +1,22 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RedisNonceStore 基于 redis 的 nonce 存储，实现 secure.NonceStore，多实例部署时共享
+type RedisNonceStore struct {
+	rdb    redis.Cmdable
+	prefix string
+}
+
+func NewRedisNonceStore(rdb redis.Cmdable, prefix string) *RedisNonceStore {
+	return &RedisNonceStore{rdb: rdb, prefix: prefix}
+}
+
+func (r *RedisNonceStore) CheckAndStore(nonce string, expiration time.Duration) (bool, error) {
+	return r.rdb.SetNX(context.Background(), r.prefix+nonce, 1, expiration).Result()
+}
@@ -0,0 +1,151 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var (
+	// ErrNoWorkerIdAvailable 分配 worker id 时所有值都已被占用
+	ErrNoWorkerIdAvailable = errors.New("没有可用的 worker id")
+	ErrInvalidLeaseTTL     = errors.New("租约有效期至少为 3 毫秒")
+)
+
+// 只有持有者才能续期和释放
+var (
+	renewLeaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+
+	releaseLeaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+)
+
+// WorkerLease 基于 redis 租约分配的 worker id，用于 snowflake.New，
+// 后台按 ttl/3 的间隔续期，续期连续失败到下次续期前租约可能过期时关闭 Lost，进程退出前应调用 Release
+type WorkerLease struct {
+	rdb      redis.Cmdable
+	key      string
+	token    string
+	workerId int64
+	ttl      time.Duration
+	now      func() time.Time
+
+	lost     chan struct{}
+	stop     chan struct{}
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+// AcquireWorkerId 从 0 到 maxWorkerId 中获取第一个未被占用的 worker id，
+// 键为 prefix 加 worker id，例如 "order:worker:3"
+func AcquireWorkerId(ctx context.Context, rdb redis.Cmdable, prefix string, maxWorkerId int64, ttl time.Duration) (*WorkerLease, error) {
+	return acquireWorkerId(ctx, rdb, prefix, maxWorkerId, ttl, time.Now)
+}
+
+func acquireWorkerId(ctx context.Context, rdb redis.Cmdable, prefix string, maxWorkerId int64, ttl time.Duration, now func() time.Time) (*WorkerLease, error) {
+	if ttl < 3*time.Millisecond {
+		return nil, ErrInvalidLeaseTTL
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	token := hex.EncodeToString(b)
+
+	for id := int64(0); id <= maxWorkerId; id++ {
+		key := prefix + strconv.FormatInt(id, 10)
+		acquiredAt := now()
+		ok, err := rdb.SetNX(ctx, key, token, ttl).Result()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		l := &WorkerLease{
+			rdb:      rdb,
+			key:      key,
+			token:    token,
+			workerId: id,
+			ttl:      ttl,
+			now:      now,
+			lost:     make(chan struct{}),
+			stop:     make(chan struct{}),
+			stopped:  make(chan struct{}),
+		}
+		go l.renew(acquiredAt)
+		return l, nil
+	}
+
+	return nil, fmt.Errorf("%w，已占用 0 到 %d", ErrNoWorkerIdAvailable, maxWorkerId)
+}
+
+// WorkerId 返回分配到的 worker id
+func (l *WorkerLease) WorkerId() int64 {
+	return l.workerId
+}
+
+// Lost 租约已被其他实例占用，或续期失败导致租约可能已过期时关闭，此时应停止使用该 worker id 生成 ID
+func (l *WorkerLease) Lost() <-chan struct{} {
+	return l.lost
+}
+
+// Release 停止续期并释放 worker id
+func (l *WorkerLease) Release(ctx context.Context) error {
+	l.stopOnce.Do(func() {
+		close(l.stop)
+	})
+	<-l.stopped
+
+	return releaseLeaseScript.Run(ctx, l.rdb, []string{l.key}, l.token).Err()
+}
+
+// renew renewedAt 为最近一次成功续期发出请求的时间，租约最晚在 renewedAt+ttl 过期
+func (l *WorkerLease) renew(renewedAt time.Time) {
+	defer close(l.stopped)
+
+	interval := l.ttl / 3
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			start := l.now()
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			n, err := renewLeaseScript.Run(ctx, l.rdb, []string{l.key}, l.token, l.ttl.Milliseconds()).Int()
+			cancel()
+
+			switch {
+			case err == nil && n != 0:
+				renewedAt = start
+			case err == nil:
+				// 租约已不属于自己
+				close(l.lost)
+				return
+			case l.now().Sub(renewedAt)+interval >= l.ttl:
+				// 网络错误时下次再试，下次续期前租约可能已过期时不再使用
+				close(l.lost)
+				return
+			}
+		case <-l.stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// fakeRedis 只实现租约用到的 SetNX 和两个脚本，fail 为 true 时模拟网络错误
+type fakeRedis struct {
+	redis.Cmdable
+
+	mux     sync.Mutex
+	values  map[string]string
+	expires map[string]time.Time
+	fail    bool
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{values: make(map[string]string), expires: make(map[string]time.Time)}
+}
+
+// get 需要持有锁
+func (f *fakeRedis) get(key string) (string, bool) {
+	if expireAt, ok := f.expires[key]; ok && !time.Now().Before(expireAt) {
+		delete(f.values, key)
+		delete(f.expires, key)
+	}
+	value, ok := f.values[key]
+	return value, ok
+}
+
+func (f *fakeRedis) set(key string, value string, ttl time.Duration) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	f.values[key] = value
+	f.expires[key] = time.Now().Add(ttl)
+}
+
+func (f *fakeRedis) setFail(fail bool) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	f.fail = fail
+}
+
+func (f *fakeRedis) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	if f.fail {
+		return redis.NewBoolResult(false, errors.New("connection refused"))
+	}
+	if _, ok := f.get(key); ok {
+		return redis.NewBoolResult(false, nil)
+	}
+	f.values[key] = fmt.Sprint(value)
+	f.expires[key] = time.Now().Add(expiration)
+	return redis.NewBoolResult(true, nil)
+}
+
+func (f *fakeRedis) EvalSha(ctx context.Context, sha1 string, keys []string, args ...interface{}) *redis.Cmd {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	if f.fail {
+		return redis.NewCmdResult(nil, errors.New("connection refused"))
+	}
+	if value, ok := f.get(keys[0]); !ok || value != fmt.Sprint(args[0]) {
+		return redis.NewCmdResult(int64(0), nil)
+	}
+
+	switch sha1 {
+	case renewLeaseScript.Hash():
+		f.expires[keys[0]] = time.Now().Add(time.Duration(args[1].(int64)) * time.Millisecond)
+	case releaseLeaseScript.Hash():
+		delete(f.values, keys[0])
+		delete(f.expires, keys[0])
+	default:
+		return redis.NewCmdResult(nil, errors.New("NOSCRIPT unknown script"))
+	}
+	return redis.NewCmdResult(int64(1), nil)
+}
+
+func waitLost(l *WorkerLease, timeout time.Duration) bool {
+	select {
+	case <-l.Lost():
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+func TestAcquireWorkerId(t *testing.T) {
+	ctx := context.Background()
+	rdb := newFakeRedis()
+
+	if _, err := AcquireWorkerId(ctx, rdb, "order:worker:", 1, time.Millisecond); err != ErrInvalidLeaseTTL {
+		t.Errorf("AcquireWorkerId() err = %v", err)
+	}
+
+	l0, err := AcquireWorkerId(ctx, rdb, "order:worker:", 1, time.Minute)
+	if err != nil || l0.WorkerId() != 0 {
+		t.Fatalf("AcquireWorkerId() got = %v, err = %v", l0, err)
+	}
+	l1, err := AcquireWorkerId(ctx, rdb, "order:worker:", 1, time.Minute)
+	if err != nil || l1.WorkerId() != 1 {
+		t.Fatalf("AcquireWorkerId() got = %v, err = %v", l1, err)
+	}
+	if _, err := AcquireWorkerId(ctx, rdb, "order:worker:", 1, time.Minute); !errors.Is(err, ErrNoWorkerIdAvailable) {
+		t.Errorf("AcquireWorkerId() err = %v", err)
+	}
+
+	// 释放后可以被再次获取
+	if err := l0.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	l2, err := AcquireWorkerId(ctx, rdb, "order:worker:", 1, time.Minute)
+	if err != nil || l2.WorkerId() != 0 {
+		t.Errorf("AcquireWorkerId() got = %v, err = %v", l2, err)
+	}
+	l1.Release(ctx)
+	l2.Release(ctx)
+}
+
+func TestWorkerLeaseRenew(t *testing.T) {
+	ctx := context.Background()
+	rdb := newFakeRedis()
+
+	l, err := AcquireWorkerId(ctx, rdb, "order:worker:", 0, 30*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 超过 ttl 后仍然持有租约
+	if waitLost(l, 100*time.Millisecond) {
+		t.Fatal("lease should be renewed")
+	}
+	if _, err := AcquireWorkerId(ctx, rdb, "order:worker:", 0, 30*time.Millisecond); !errors.Is(err, ErrNoWorkerIdAvailable) {
+		t.Errorf("AcquireWorkerId() err = %v", err)
+	}
+
+	if err := l.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	rdb.mux.Lock()
+	if _, ok := rdb.get("order:worker:0"); ok {
+		t.Errorf("lease should be released")
+	}
+	rdb.mux.Unlock()
+}
+
+func TestWorkerLeaseStolen(t *testing.T) {
+	ctx := context.Background()
+	rdb := newFakeRedis()
+
+	l, err := AcquireWorkerId(ctx, rdb, "order:worker:", 0, 30*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rdb.set("order:worker:0", "other", time.Minute)
+	if !waitLost(l, time.Second) {
+		t.Fatal("lease should be lost")
+	}
+
+	// 不会释放其他实例的租约
+	l.Release(ctx)
+	rdb.mux.Lock()
+	if value, _ := rdb.get("order:worker:0"); value != "other" {
+		t.Errorf("value got = %s", value)
+	}
+	rdb.mux.Unlock()
+}
+
+func TestWorkerLeaseRenewFailure(t *testing.T) {
+	ctx := context.Background()
+	rdb := newFakeRedis()
+
+	ttl := 60 * time.Millisecond
+	l, err := AcquireWorkerId(ctx, rdb, "order:worker:", 0, ttl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rdb.mux.Lock()
+	expireAt := rdb.expires["order:worker:0"]
+	rdb.mux.Unlock()
+
+	// 续期一直失败时在租约过期前关闭 Lost
+	rdb.setFail(true)
+	if !waitLost(l, time.Second) {
+		t.Fatal("lease should be lost")
+	}
+	if now := time.Now(); now.After(expireAt.Add(ttl / 3)) {
+		t.Errorf("lost closed at %s, lease expires at %s", now, expireAt)
+	}
+	l.Release(ctx)
+}
@@ -0,0 +1,66 @@
+package ginx
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/chengjianxi/goc/haoxin"
+	"github.com/chengjianxi/goc/secure"
+	"github.com/gin-gonic/gin"
+)
+
+// gin.Context 中保存 token 内容的 key
+const (
+	ContextKeyClaims    = "claims"
+	ContextKeyUserId    = "userid"
+	ContextKeyMachineId = "machineid"
+)
+
+// JWTAuth 返回校验 access token 的中间件，token 从 `Authorization: Bearer <token>` 中读取
+//
+// 校验通过后将 Claims、userid、machineid 写入 gin.Context，并使用 token 中的值覆盖请求的
+// userid、machineid Header 和请求 context 中的 haoxin.Metadata，下游的日志和 rpc 调用不再信任客户端传入的 Header。
+// 可以放在 haoxin.Middleware、tracing.Middleware 之前或之后。
+//
+// use it:
+//
+//	keys, _ := secure.LoadJWKSFile("jwks.json")
+//	router.Use(ginx.JWTAuth(secure.NewTokenVerifier(keys, "haoxin")))
+func JWTAuth(verifier *secure.TokenVerifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		auth := c.GetHeader("Authorization")
+		if !strings.HasPrefix(auth, "Bearer ") {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"code": http.StatusUnauthorized, "msg": "缺少 token"})
+			return
+		}
+
+		claims, err := verifier.Verify(strings.TrimPrefix(auth, "Bearer "), secure.TokenTypeAccess)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"code": http.StatusUnauthorized, "msg": err.Error()})
+			return
+		}
+
+		c.Set(ContextKeyClaims, claims)
+		c.Set(ContextKeyUserId, claims.UserId)
+		c.Set(ContextKeyMachineId, claims.MachineId)
+		c.Request.Header.Set(haoxin.HeaderUserId, claims.UserId)
+		c.Request.Header.Set(haoxin.HeaderMachineId, claims.MachineId)
+
+		// 之前的中间件已经从 Header 中读取了请求信息
+		m, ok := haoxin.FromContext(c.Request.Context())
+		if !ok {
+			m = haoxin.MetadataFromRequest(c.Request)
+		}
+		m.UserId, m.MachineId = claims.UserId, claims.MachineId
+		c.Request = c.Request.WithContext(haoxin.NewContext(c.Request.Context(), m))
+
+		c.Next()
+	}
+}
+
+// GetClaims 获取 JWTAuth 写入的 token 内容，未经过 JWTAuth 时返回 nil
+func GetClaims(c *gin.Context) *secure.Claims {
+	claims, _ := c.Get(ContextKeyClaims)
+	result, _ := claims.(*secure.Claims)
+	return result
+}
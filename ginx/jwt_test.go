@@ -0,0 +1,104 @@
+package ginx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chengjianxi/goc/haoxin"
+	"github.com/chengjianxi/goc/secure"
+	"github.com/gin-gonic/gin"
+)
+
+func TestJWTAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	key := secure.JWTKey{Id: "hs", Algorithm: secure.JWTHS256, Key: []byte("secret")}
+	router := gin.New()
+	router.Use(JWTAuth(secure.NewTokenVerifier(secure.NewKeySet(key), "haoxin")))
+
+	var claims *secure.Claims
+	router.GET("/me", func(c *gin.Context) {
+		claims = GetClaims(c)
+		c.String(http.StatusOK, c.GetHeader("userid")+","+c.GetHeader("machineid")+","+c.GetString(ContextKeyUserId))
+	})
+
+	pair, err := secure.NewTokenIssuer(key, "haoxin").Issue("10001", "m1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// token 中的 userid、machineid 覆盖客户端传入的 Header
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Authorization", "Bearer "+pair.AccessToken)
+	req.Header.Set("userid", "admin")
+	req.Header.Set("machineid", "forged")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "10001,m1,10001" || claims == nil || claims.UserId != "10001" {
+		t.Errorf("response got = %d %s, claims = %+v", w.Code, w.Body.String(), claims)
+	}
+
+	noExp, _ := secure.SignJWT(secure.Claims{UserId: "1", TokenType: secure.TokenTypeAccess, Issuer: "haoxin"}, key)
+	expired, _ := secure.SignJWT(secure.Claims{UserId: "1", TokenType: secure.TokenTypeAccess, Issuer: "haoxin", ExpiresAt: time.Now().Add(-time.Hour).Unix()}, key)
+	tests := []struct {
+		name          string
+		authorization string
+		msg           string
+	}{
+		{"missing", "", "缺少 token"},
+		{"not bearer", "Basic abc", "缺少 token"},
+		{"malformed", "Bearer abc", secure.ErrTokenMalformed.Error()},
+		{"refresh token", "Bearer " + pair.RefreshToken, secure.ErrTokenInvalidClaim.Error()},
+		{"no exp", "Bearer " + noExp, secure.ErrTokenInvalidClaim.Error()},
+		{"expired", "Bearer " + expired, secure.ErrTokenExpired.Error()},
+	}
+	for _, tt := range tests {
+		claims = nil
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		if tt.authorization != "" {
+			req.Header.Set("Authorization", tt.authorization)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), tt.msg) || claims != nil {
+			t.Errorf("%s: response got = %d %s", tt.name, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestJWTAuthMetadata(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	key := secure.JWTKey{Id: "hs", Algorithm: secure.JWTHS256, Key: []byte("secret")}
+	router := gin.New()
+	router.Use(haoxin.Middleware("40"), JWTAuth(secure.NewTokenVerifier(secure.NewKeySet(key), "haoxin")))
+
+	var m haoxin.Metadata
+	router.GET("/me", func(c *gin.Context) {
+		m, _ = haoxin.FromContext(c)
+	})
+
+	pair, err := secure.NewTokenIssuer(key, "haoxin").Issue("10001", "m1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// haoxin.Middleware 已经读取的 userid、machineid 也被 token 中的值覆盖
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Authorization", "Bearer "+pair.AccessToken)
+	req.Header.Set(haoxin.HeaderSid, "s1")
+	req.Header.Set(haoxin.HeaderUserId, "admin")
+	req.Header.Set(haoxin.HeaderMachineId, "forged")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	if m != (haoxin.Metadata{Sid: "s1", UserId: "10001", MachineId: "m1"}) {
+		t.Errorf("FromContext() got = %+v", m)
+	}
+}
+
+func TestGetClaimsWithoutAuth(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	if GetClaims(c) != nil {
+		t.Errorf("GetClaims() should return nil")
+	}
+}
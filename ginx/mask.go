@@ -0,0 +1,11 @@
+package ginx
+
+import (
+	"github.com/chengjianxi/goc/secure"
+	"github.com/gin-gonic/gin"
+)
+
+// MaskedJSON 按结构体的 `mask` 标签脱敏后输出 JSON，obj 本身不会被修改
+func MaskedJSON(c *gin.Context, code int, obj interface{}) {
+	c.JSON(code, secure.MaskStruct(obj))
+}
@@ -0,0 +1,34 @@
+package ginx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type maskedUser struct {
+	Name  string `json:"name" mask:"name"`
+	Phone string `json:"phone" mask:"phone"`
+	Id    int    `json:"id"`
+}
+
+func TestMaskedJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	user := &maskedUser{Name: "张三丰", Phone: "13812345678", Id: 1}
+
+	router := gin.New()
+	router.GET("/user", func(c *gin.Context) {
+		MaskedJSON(c, http.StatusOK, user)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/user", nil))
+	if w.Code != http.StatusOK || w.Body.String() != `{"name":"张**","phone":"138****5678","id":1}` {
+		t.Errorf("response got = %d %s", w.Code, w.Body.String())
+	}
+	if user.Name != "张三丰" || user.Phone != "13812345678" {
+		t.Errorf("user should not be modified, got = %+v", user)
+	}
+}
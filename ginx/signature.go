@@ -0,0 +1,30 @@
+package ginx
+
+import (
+	"net/http"
+
+	"github.com/chengjianxi/goc/secure"
+	"github.com/gin-gonic/gin"
+)
+
+// VerifySignature 返回校验服务间调用签名的中间件，签名错误或重放的请求返回 401，
+// body 超过 Verifier.MaxBodySize 时返回 413
+//
+// use it:
+//
+//	verifier := secure.NewVerifier(map[string][]byte{"svc": key}, secure.NewMemoryNonceStore())
+//	router.Use(ginx.VerifySignature(verifier))
+func VerifySignature(verifier *secure.Verifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := verifier.Verify(c.Request); err != nil {
+			code := http.StatusUnauthorized
+			if err == secure.ErrSignBodyTooLarge {
+				code = http.StatusRequestEntityTooLarge
+			}
+			c.AbortWithStatusJSON(code, gin.H{"code": code, "msg": err.Error()})
+			return
+		}
+
+		c.Next()
+	}
+}
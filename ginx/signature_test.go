@@ -0,0 +1,66 @@
+package ginx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/chengjianxi/goc/secure"
+	"github.com/gin-gonic/gin"
+)
+
+func TestVerifySignature(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	key := []byte("secret")
+	router := gin.New()
+	router.Use(VerifySignature(secure.NewVerifier(map[string][]byte{"svc": key}, secure.NewMemoryNonceStore())))
+	router.POST("/orders", func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetHeader("userid"))
+	})
+
+	signed := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{"id":1}`))
+	signed.Header.Set("userid", "1")
+	secure.NewSigner("svc", key).Sign(signed)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, signed)
+	if w.Code != http.StatusOK || w.Body.String() != "1" {
+		t.Errorf("signed request got = %d %s", w.Code, w.Body.String())
+	}
+
+	replayed := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{"id":1}`))
+	replayed.Header = signed.Header.Clone()
+
+	tampered := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{"id":1}`))
+	secure.NewSigner("svc", key).Sign(tampered)
+	tampered.Header.Set("userid", "admin")
+
+	tests := []struct {
+		name    string
+		request *http.Request
+	}{
+		{"unsigned", httptest.NewRequest(http.MethodPost, "/orders", nil)},
+		{"replayed", replayed},
+		{"tampered", tampered},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, tt.request)
+		if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), `"code":401`) {
+			t.Errorf("%s request got = %d %s", tt.name, w.Code, w.Body.String())
+		}
+	}
+
+	verifier := secure.NewVerifier(map[string][]byte{"svc": key}, secure.NewMemoryNonceStore())
+	verifier.MaxBodySize = 4
+	router = gin.New()
+	router.Use(VerifySignature(verifier))
+	large := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{"id":1}`))
+	secure.NewSigner("svc", key).Sign(large)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, large)
+	if w.Code != http.StatusRequestEntityTooLarge || !strings.Contains(w.Body.String(), `"code":413`) {
+		t.Errorf("large request got = %d %s", w.Code, w.Body.String())
+	}
+}
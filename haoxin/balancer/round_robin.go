@@ -0,0 +1,78 @@
+package balancer
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultCooldown 调用失败的实例默认暂停选择的时间
+const DefaultCooldown = 10 * time.Second
+
+// Balancer 从服务的实例地址中选择一个，并根据调用结果调整后续的选择
+type Balancer interface {
+	Pick(name string, addrs []string) string
+	Feedback(name string, addr string, ok bool)
+}
+
+// RoundRobin 按服务轮询，调用失败的实例在 cooldown 内跳过，所有实例都失败时仍然轮询全部实例
+type RoundRobin struct {
+	cooldown time.Duration
+
+	mux      sync.Mutex
+	counters map[string]int
+	failed   map[string]time.Time // 服务名加地址，失败实例恢复选择的时间
+	now      func() time.Time
+}
+
+// NewRoundRobin cooldown 小于等于 0 时使用 DefaultCooldown
+func NewRoundRobin(cooldown time.Duration) *RoundRobin {
+	if cooldown <= 0 {
+		cooldown = DefaultCooldown
+	}
+	return &RoundRobin{
+		cooldown: cooldown,
+		counters: make(map[string]int),
+		failed:   make(map[string]time.Time),
+		now:      time.Now,
+	}
+}
+
+func (r *RoundRobin) Pick(name string, addrs []string) string {
+	if len(addrs) == 0 {
+		return ""
+	}
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	now := r.now()
+	n := r.counters[name]
+	for i := 0; i < len(addrs); i++ {
+		addr := addrs[(n+i)%len(addrs)]
+		key := name + " " + addr
+		if until, ok := r.failed[key]; ok {
+			if now.Before(until) {
+				continue
+			}
+			delete(r.failed, key)
+		}
+		r.counters[name] = (n + i + 1) % len(addrs)
+		return addr
+	}
+
+	r.counters[name] = (n + 1) % len(addrs)
+	return addrs[n%len(addrs)]
+}
+
+// Feedback 调用失败时暂停选择该实例，成功时立即恢复
+func (r *RoundRobin) Feedback(name string, addr string, ok bool) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	key := name + " " + addr
+	if ok {
+		delete(r.failed, key)
+	} else {
+		r.failed[key] = r.now().Add(r.cooldown)
+	}
+}
@@ -0,0 +1,45 @@
+package balancer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRoundRobin(t *testing.T) {
+	r := NewRoundRobin(time.Second)
+	now := time.Unix(1700000000, 0)
+	r.now = func() time.Time { return now }
+
+	addrs := []string{"a", "b", "c"}
+	pick := func() (picked string) {
+		for i := 0; i < 3; i++ {
+			picked += r.Pick("svc", addrs)
+		}
+		return picked
+	}
+	if got := pick(); got != "abc" {
+		t.Errorf("Pick() got = %s", got)
+	}
+
+	r.Feedback("svc", "b", false)
+	if got := pick(); got != "aca" {
+		t.Errorf("Pick() got = %s", got)
+	}
+
+	// 所有实例都失败时仍然轮询
+	r.Feedback("svc", "a", false)
+	r.Feedback("svc", "c", false)
+	if got := r.Pick("svc", addrs); got == "" {
+		t.Errorf("Pick() got empty")
+	}
+
+	// cooldown 后恢复
+	now = now.Add(time.Second)
+	if got := pick(); len(got) != 3 || got[0] == got[1] || got[1] == got[2] || got[0] == got[2] {
+		t.Errorf("Pick() got = %s", got)
+	}
+
+	if got := r.Pick("svc", nil); got != "" {
+		t.Errorf("Pick() got = %s", got)
+	}
+}
@@ -1,18 +1,35 @@
 package eureka
 
 import (
+	"context"
 	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/chengjianxi/goc/cache"
 	"github.com/chengjianxi/goc/haoxin/balancer"
+	"github.com/chengjianxi/goc/haoxin/rpc"
 	eureka_client "github.com/xuanbo/eureka-client"
 )
 
+// MetadataWorkerId 实例 metadata 中保存 snowflake worker id 的键
+const MetadataWorkerId = "workerId"
+
+// 更新 metadata 使用的 http.Client，避免 eureka 无响应时一直阻塞
+var metadataClient = &http.Client{Timeout: 5 * time.Second}
+
 type Eureka struct {
 	eureka     *eureka_client.Client
 	serverPool *balancer.NamesServerPool
 	mutex      sync.RWMutex
+
+	zone       string
+	appName    string
+	instanceId string
 }
 
 // 启动服务注册发现
@@ -20,19 +37,23 @@ func Start(zone string, appName string, port int) *Eureka {
 	// 服务注册 github.com/xuanbo/eureka-client
 	// 创建 eureka client
 	// zone := fmt.Sprintf("http://%s:%d/eureka/", c.Eureka.Host, c.Eureka.Port)
+	instanceId := fmt.Sprintf("%s:%s:%d", strings.ToLower(appName), eureka_client.GetLocalIP(), port)
 	eureka := eureka_client.NewClient(&eureka_client.Config{
 		DefaultZone:           zone,
 		App:                   appName,
 		Port:                  port,
 		RenewalIntervalInSecs: 30,
 		DurationInSecs:        30,
-		InstanceID:            fmt.Sprintf("%s:%s:%d", strings.ToLower(appName), eureka_client.GetLocalIP(), port),
+		InstanceID:            instanceId,
 	})
 	// 启动 eureka client, register、heartbeat、refresh
 	eureka.Start()
 	return &Eureka{
 		eureka:     eureka,
 		serverPool: balancer.NewNamesServerPool(),
+		zone:       zone,
+		appName:    appName,
+		instanceId: instanceId,
 	}
 }
 
@@ -40,6 +61,25 @@ func (c *Eureka) GetServiceAddress(name string) string {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
+	c.serverPool.SetServerAddrs(name, c.upAddrs(name))
+	return c.serverPool.GetServerAddr(name)
+}
+
+// Resolve 返回服务所有 UP 实例的地址，实现 rpc.Resolver
+//
+// use it:
+//
+//	rpc.SetResolver(e)
+//	rpc.Rpc(request, http.MethodGet, "svc://USER-SVC", "/users/1", nil, nil)
+func (c *Eureka) Resolve(ctx context.Context, name string) ([]string, error) {
+	urls := c.upAddrs(name)
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("%w：%s", rpc.ErrServiceNotFound, name)
+	}
+	return urls, nil
+}
+
+func (c *Eureka) upAddrs(name string) []string {
 	instances := c.eureka.GetApplicationInstance(name)
 	urls := make([]string, 0)
 	for _, instance := range instances {
@@ -47,6 +87,57 @@ func (c *Eureka) GetServiceAddress(name string) string {
 			urls = append(urls, instance.HomePageURL)
 		}
 	}
-	c.serverPool.SetServerAddrs(name, urls)
-	return c.serverPool.GetServerAddr(name)
+	return urls
+}
+
+// WorkerId 为当前实例分配 snowflake worker id：跳过同一服务其他 UP 实例 metadata 中已使用的，
+// 取 0 到 maxWorkerId 中最小的可用值，并写入当前实例的 metadata
+//
+// 注册表按周期刷新，多个实例同时启动时可能分配到相同的值，需要严格唯一时使用 cache.AcquireWorkerId
+func (c *Eureka) WorkerId(maxWorkerId int64) (int64, error) {
+	used := make(map[int64]bool)
+	for _, instance := range c.eureka.GetApplicationInstance(c.appName) {
+		if instance.InstanceID == c.instanceId || instance.Status != "UP" {
+			continue
+		}
+		if value, ok := instance.Metadata[MetadataWorkerId]; ok {
+			if id, err := strconv.ParseInt(fmt.Sprint(value), 10, 64); err == nil {
+				used[id] = true
+			}
+		}
+	}
+
+	for id := int64(0); id <= maxWorkerId; id++ {
+		if used[id] {
+			continue
+		}
+		if err := c.updateMetadata(MetadataWorkerId, strconv.FormatInt(id, 10)); err != nil {
+			return 0, err
+		}
+		return id, nil
+	}
+
+	return 0, fmt.Errorf("%w，已占用 0 到 %d", cache.ErrNoWorkerIdAvailable, maxWorkerId)
+}
+
+// 通过 eureka REST 接口更新当前实例的 metadata
+func (c *Eureka) updateMetadata(key string, value string) error {
+	u := fmt.Sprintf("%s/apps/%s/%s/metadata?%s", strings.TrimSuffix(c.zone, "/"),
+		url.PathEscape(strings.ToUpper(c.appName)), url.PathEscape(c.instanceId),
+		url.Values{key: []string{value}}.Encode())
+	req, err := http.NewRequest(http.MethodPut, u, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := metadataClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("更新 eureka metadata 失败，%s", resp.Status)
+	}
+	return nil
 }
@@ -1,19 +1,205 @@
 package haoxin
 
 import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
 	"fmt"
-	"math/rand"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// Sid/Tid 格式：模块(2)+yyyyMMddHHmmss(14)+序号(6)，序号为节点(2)+计数(4)
+const (
+	moduleIdLen = 2
+	sidTimeLen  = 14
+	sidSeqLen   = 6
+	sidLen      = moduleIdLen + sidTimeLen + sidSeqLen
+
+	MaxNodeId = 99
+	seqCount  = 10000   // 每个节点每秒最多生成的不重复序号
+	seqMax    = 1000000 // 未指定节点 ID 时序号的范围
+
+	// NodeIdEnv 环境变量，设置后作为默认节点 ID，例如 StatefulSet 的副本序号，值不正确时启动 panic
+	NodeIdEnv = "HAOXIN_NODE_ID"
+)
+
+var (
+	ErrInvalidModuleId = errors.New("模块 ID 必须是 2 位字母或数字")
+	ErrInvalidNodeId   = fmt.Errorf("节点 ID 必须在 0 到 %d 之间", MaxNodeId)
+	ErrInvalidSid      = errors.New("sid 格式不正确")
+)
+
+// Generator Sid/Tid 生成器，不同节点使用不同的节点 ID 时生成的序号不会重复，
+// 同一节点每秒最多生成 10000 个，用完后等到下一秒再生成
+type Generator struct {
+	nodeId int // 小于 0 表示未指定，序号使用全部 6 位，不同副本之间可能重复
+
+	mux    sync.Mutex
+	second int64 // 当前的秒
+	start  int   // 当前秒的起始序号，随机值，避免进程重启后同一秒内重复
+	issued int   // 当前秒已生成的个数
+	now    func() time.Time
+	sleep  func(time.Duration)
+}
+
+// NewGenerator 创建生成器，nodeId 在 0 到 MaxNodeId 之间，例如副本的序号
+func NewGenerator(nodeId int) (*Generator, error) {
+	if nodeId < 0 || nodeId > MaxNodeId {
+		return nil, ErrInvalidNodeId
+	}
+	return newGenerator(nodeId), nil
+}
+
+func newGenerator(nodeId int) *Generator {
+	return &Generator{nodeId: nodeId, second: -1, now: time.Now, sleep: time.Sleep}
+}
+
+// Generate 生成 Sid/Tid
+func (g *Generator) Generate(moduleId string) (string, error) {
+	if err := ValidateModuleId(moduleId); err != nil {
+		return "", err
+	}
+	return g.generate(moduleId), nil
+}
+
+func (g *Generator) generate(moduleId string) string {
+	count := seqCount
+	if g.nodeId < 0 {
+		count = seqMax
+	}
+
+	g.mux.Lock()
+	defer g.mux.Unlock()
+
+	now := g.now()
+	for now.Unix() == g.second && g.issued >= count {
+		// 当前秒的序号已用完，等到下一秒，避免重复
+		g.sleep(now.Truncate(time.Second).Add(time.Second).Sub(now))
+		now = g.now()
+	}
+	if now.Unix() != g.second {
+		var b [4]byte
+		rand.Read(b[:])
+		g.second, g.start, g.issued = now.Unix(), int(binary.BigEndian.Uint32(b[:])%uint32(count)), 0
+	}
+
+	seq := (g.start + g.issued) % count
+	g.issued++
+	if g.nodeId >= 0 {
+		seq += g.nodeId * seqCount
+	}
+	return fmt.Sprintf("%s%s%06d", moduleId, now.Format("20060102150405"), seq)
+}
+
+// ValidateModuleId 检查模块 ID 是否为 2 位字母或数字
+func ValidateModuleId(moduleId string) error {
+	if len(moduleId) != moduleIdLen {
+		return ErrInvalidModuleId
+	}
+	for _, c := range moduleId {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') {
+			return ErrInvalidModuleId
+		}
+	}
+	return nil
+}
+
+var (
+	defaultGenerator atomic.Value
+	nodeIdWarning    sync.Once
+)
+
+// 默认节点 ID 从 NodeIdEnv 读取，未设置时不指定节点 ID，设置了但不正确时 panic
+func init() {
+	g := newGenerator(-1)
+	if value := os.Getenv(NodeIdEnv); value != "" {
+		nodeId, err := strconv.Atoi(value)
+		if err != nil || nodeId < 0 || nodeId > MaxNodeId {
+			panic(fmt.Errorf("环境变量 %s=%q 不正确，%w", NodeIdEnv, value, ErrInvalidNodeId))
+		}
+		g = newGenerator(nodeId)
+	}
+	defaultGenerator.Store(g)
+}
+
+// 使用默认生成器并且没有指定节点 ID 时输出一次警告
+func defaultGen() *Generator {
+	g := defaultGenerator.Load().(*Generator)
+	if g.nodeId < 0 {
+		nodeIdWarning.Do(func() {
+			fmt.Fprintf(os.Stderr, "没有指定节点 ID，多副本部署时生成的 Sid 可能重复，请调用 haoxin.SetNodeId 或设置环境变量 %s\n", NodeIdEnv)
+		})
+	}
+	return g
+}
+
+// SetNodeId 设置 NewSid/NewTid 使用的节点 ID，多副本部署时必须为每个副本指定不同的节点 ID
+// （或设置 NodeIdEnv），否则不同副本生成的 Sid 可能重复，第一次生成或创建 Middleware 时会输出警告，
+// 应在创建 Middleware 之前调用
+func SetNodeId(nodeId int) error {
+	g, err := NewGenerator(nodeId)
+	if err != nil {
+		return err
+	}
+	defaultGenerator.Store(g)
+	return nil
+}
+
+// NewSid 生成 Sid，为兼容以前的调用不校验模块 ID，模块 ID 不是 2 位时生成的 Sid 无法通过 ParseSid 解析，
+// 新代码应使用 GenerateSid
 func NewSid(moduleId string) string {
-	seq := rand.Intn(1000000)
-	t := time.Now().Format("20060102150405")
-	return fmt.Sprintf("%s%s%06d", moduleId, t, seq)
+	return defaultGen().generate(moduleId)
 }
 
+// NewTid 生成 Tid，格式与 Sid 相同，与 NewSid 一样不校验模块 ID
 func NewTid(moduleId string) string {
-	seq := rand.Intn(1000000)
-	t := time.Now().Format("20060102150405")
-	return fmt.Sprintf("%s%s%06d", moduleId, t, seq)
+	return NewSid(moduleId)
+}
+
+// GenerateSid 生成 Sid，模块 ID 不正确时返回 ErrInvalidModuleId
+func GenerateSid(moduleId string) (string, error) {
+	return defaultGen().Generate(moduleId)
+}
+
+// GenerateTid 生成 Tid，格式与 Sid 相同，模块 ID 不正确时返回 ErrInvalidModuleId
+func GenerateTid(moduleId string) (string, error) {
+	return GenerateSid(moduleId)
+}
+
+// SidInfo Sid/Tid 包含的信息
+type SidInfo struct {
+	ModuleId string
+	Time     time.Time // 本地时间，精确到秒
+	Seq      int
+}
+
+// ParseSid 解析 Sid，Tid 格式相同也可以使用
+func ParseSid(sid string) (SidInfo, error) {
+	if len(sid) != sidLen {
+		return SidInfo{}, ErrInvalidSid
+	}
+
+	moduleId := sid[:moduleIdLen]
+	if err := ValidateModuleId(moduleId); err != nil {
+		return SidInfo{}, ErrInvalidSid
+	}
+
+	digits := sid[moduleIdLen:]
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return SidInfo{}, ErrInvalidSid
+		}
+	}
+
+	t, err := time.ParseInLocation("20060102150405", digits[:sidTimeLen], time.Local)
+	if err != nil {
+		return SidInfo{}, fmt.Errorf("%w，%s", ErrInvalidSid, err)
+	}
+	seq, _ := strconv.Atoi(digits[sidTimeLen:])
+
+	return SidInfo{ModuleId: moduleId, Time: t, Seq: seq}, nil
 }
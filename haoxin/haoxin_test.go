@@ -0,0 +1,99 @@
+package haoxin
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGenerator(t *testing.T) {
+	g, err := NewGenerator(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2023, 1, 2, 3, 4, 5, 0, time.Local)
+	g.now = func() time.Time { return now }
+	var slept time.Duration
+	g.sleep = func(d time.Duration) {
+		slept += d
+		now = now.Add(d)
+	}
+
+	seen := make(map[string]bool)
+	for i := 0; i < seqCount; i++ {
+		sid, err := g.Generate("40")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seen[sid] {
+			t.Fatalf("duplicate sid %s", sid)
+		}
+		seen[sid] = true
+	}
+
+	if slept != 0 {
+		t.Errorf("slept %s before the sequence is used up", slept)
+	}
+
+	// 当前秒的序号用完后等到下一秒
+	start := now
+	sid, _ := g.Generate("40")
+	info, err := ParseSid(sid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.ModuleId != "40" || !info.Time.Equal(start.Add(time.Second)) || info.Seq/seqCount != 7 || slept != time.Second || seen[sid] {
+		t.Errorf("ParseSid() got = %+v, slept = %s", info, slept)
+	}
+
+	if _, err := g.Generate("4"); err != ErrInvalidModuleId {
+		t.Errorf("Generate() err = %v", err)
+	}
+	if _, err := NewGenerator(MaxNodeId + 1); err != ErrInvalidNodeId {
+		t.Errorf("NewGenerator() err = %v", err)
+	}
+}
+
+func TestParseSid(t *testing.T) {
+	tests := []string{
+		"",
+		"4020230102030405000001x",
+		"4 20230102030405000001",
+		"402023010203040500000a",
+		"4020231302030405000001",
+	}
+	for _, sid := range tests {
+		if _, err := ParseSid(sid); !errors.Is(err, ErrInvalidSid) {
+			t.Errorf("ParseSid(%q) err = %v", sid, err)
+		}
+	}
+
+	info, err := ParseSid(NewTid("ab"))
+	if err != nil || info.ModuleId != "ab" {
+		t.Errorf("ParseSid() got = %+v, err = %v", info, err)
+	}
+}
+
+func TestNewSidCompatible(t *testing.T) {
+	// 兼容以前的调用，模块 ID 不正确时不 panic
+	for _, moduleId := range []string{"4", "401"} {
+		if sid := NewSid(moduleId); len(sid) != len(moduleId)+sidTimeLen+sidSeqLen || sid[:len(moduleId)] != moduleId {
+			t.Errorf("NewSid(%s) got = %s", moduleId, sid)
+		}
+		if _, err := GenerateSid(moduleId); err != ErrInvalidModuleId {
+			t.Errorf("GenerateSid(%s) err = %v", moduleId, err)
+		}
+	}
+
+	// 未指定节点 ID 时序号使用全部 6 位
+	g := newGenerator(-1)
+	now := time.Date(2023, 1, 2, 3, 4, 5, 0, time.Local)
+	g.now = func() time.Time { return now }
+	g.second, g.start = now.Unix(), seqMax-1
+	if sid := g.generate("40"); sid != "4020230102030405999999" {
+		t.Errorf("generate() got = %s", sid)
+	}
+	if sid := g.generate("40"); sid != "4020230102030405000000" {
+		t.Errorf("generate() got = %s", sid)
+	}
+}
@@ -0,0 +1,261 @@
+package log
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// OverflowPolicy 缓冲区满时的处理方式
+type OverflowPolicy int
+
+const (
+	OverflowBlock      OverflowPolicy = iota // 阻塞等待，不丢日志
+	OverflowDropNewest                       // 丢弃新写入的日志
+	OverflowDropOldest                       // 丢弃缓冲区中最早的日志
+)
+
+var ErrAsyncWriterClosed = errors.New("日志写入器已关闭")
+
+// AsyncOptions 异步写入配置
+type AsyncOptions struct {
+	BufferSize    int            // 缓冲的日志条数，默认 8192
+	Policy        OverflowPolicy // 缓冲区满时的处理方式，默认阻塞
+	FlushInterval time.Duration  // 定时刷新到底层 writer 的间隔，默认 1 秒
+}
+
+// AsyncStats 异步写入的统计
+type AsyncStats struct {
+	Written  uint64 // 已写出到底层 writer 的条数，刷新成功后才计入
+	Dropped  uint64 // 缓冲区满或写入底层 writer 出错被丢弃的条数
+	Errors   uint64 // 写入底层 writer 出错的次数
+	Buffered int    // 当前缓冲的条数
+}
+
+// AsyncWriter 异步写入日志，日志先进入有界环形缓冲区，由后台 goroutine 批量写入底层 writer
+//
+// use it:
+//
+//	writer, _ := log.InitLogWithOptions(svcName, log.DefaultRotateOptions(logPath, svcName))
+//	async := log.NewAsyncWriter(writer, log.AsyncOptions{Policy: log.OverflowDropNewest})
+//	logrus.SetOutput(async)
+//	defer async.Close()
+type AsyncWriter struct {
+	out  io.Writer
+	opts AsyncOptions
+
+	ring    [][]byte
+	head    int
+	count   int
+	closed  bool
+	mux     sync.Mutex
+	notFull *sync.Cond
+
+	signal  chan struct{}
+	flushes chan chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+
+	written uint64
+	dropped uint64
+	errors  uint64
+	pending uint64 // 已写入 bufio.Writer 还没有刷新的条数，只在后台 goroutine 中使用
+
+	// OnError 写入底层 writer 出错时回调，默认输出到 os.Stderr
+	OnError func(error)
+}
+
+// NewAsyncWriter 创建异步写入器，Close 时会关闭实现了 io.Closer 的 out
+func NewAsyncWriter(out io.Writer, opts AsyncOptions) *AsyncWriter {
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = 8192
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = time.Second
+	}
+
+	w := &AsyncWriter{
+		out:     out,
+		opts:    opts,
+		ring:    make([][]byte, opts.BufferSize),
+		signal:  make(chan struct{}, 1),
+		flushes: make(chan chan struct{}),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	w.notFull = sync.NewCond(&w.mux)
+
+	go w.run()
+	return w
+}
+
+// Write 将日志放入缓冲区，p 会被复制，调用方可以复用
+func (w *AsyncWriter) Write(p []byte) (int, error) {
+	entry := make([]byte, len(p))
+	copy(entry, p)
+
+	w.mux.Lock()
+	for !w.closed && w.count == len(w.ring) {
+		switch w.opts.Policy {
+		case OverflowDropNewest:
+			w.mux.Unlock()
+			atomic.AddUint64(&w.dropped, 1)
+			return len(p), nil
+		case OverflowDropOldest:
+			w.ring[w.head] = nil
+			w.head = (w.head + 1) % len(w.ring)
+			w.count--
+			atomic.AddUint64(&w.dropped, 1)
+		default:
+			w.notFull.Wait()
+		}
+	}
+	if w.closed {
+		w.mux.Unlock()
+		return 0, ErrAsyncWriterClosed
+	}
+
+	w.ring[(w.head+w.count)%len(w.ring)] = entry
+	w.count++
+	w.mux.Unlock()
+
+	select {
+	case w.signal <- struct{}{}:
+	default:
+	}
+	return len(p), nil
+}
+
+// Flush 等待缓冲区中的日志全部写入底层 writer
+func (w *AsyncWriter) Flush() {
+	done := make(chan struct{})
+	select {
+	case w.flushes <- done:
+		<-done
+	case <-w.stopped:
+	}
+}
+
+// Close 写完缓冲区中的日志后停止后台 goroutine，并关闭底层 writer
+func (w *AsyncWriter) Close() error {
+	w.mux.Lock()
+	if w.closed {
+		w.mux.Unlock()
+		return nil
+	}
+	w.closed = true
+	w.notFull.Broadcast()
+	w.mux.Unlock()
+
+	close(w.done)
+	<-w.stopped
+
+	if closer, ok := w.out.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Stats 返回写入统计
+func (w *AsyncWriter) Stats() AsyncStats {
+	w.mux.Lock()
+	buffered := w.count
+	w.mux.Unlock()
+
+	return AsyncStats{
+		Written:  atomic.LoadUint64(&w.written),
+		Dropped:  atomic.LoadUint64(&w.dropped),
+		Errors:   atomic.LoadUint64(&w.errors),
+		Buffered: buffered,
+	}
+}
+
+func (w *AsyncWriter) run() {
+	defer close(w.stopped)
+
+	bw := bufio.NewWriterSize(w.out, 64*1024)
+	ticker := time.NewTicker(w.opts.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([][]byte, 0, len(w.ring))
+	flush := func() { w.flush(bw) }
+
+	for {
+		select {
+		case <-w.signal:
+			batch = w.drain(batch[:0], bw)
+		case <-ticker.C:
+			batch = w.drain(batch[:0], bw)
+			flush()
+		case done := <-w.flushes:
+			batch = w.drain(batch[:0], bw)
+			flush()
+			close(done)
+		case <-w.done:
+			w.drain(batch[:0], bw)
+			flush()
+			return
+		}
+	}
+}
+
+// 取出缓冲区中的全部日志写入 bw
+func (w *AsyncWriter) drain(batch [][]byte, bw *bufio.Writer) [][]byte {
+	w.mux.Lock()
+	for w.count > 0 {
+		batch = append(batch, w.ring[w.head])
+		w.ring[w.head] = nil
+		w.head = (w.head + 1) % len(w.ring)
+		w.count--
+	}
+	w.notFull.Broadcast()
+	w.mux.Unlock()
+
+	for _, entry := range batch {
+		// 放不下时先刷新，避免 bufio.Writer 自动刷新时无法确定哪些日志已写出
+		if bw.Buffered() > 0 && len(entry) > bw.Available() {
+			w.flush(bw)
+		}
+		if _, err := bw.Write(entry); err != nil {
+			w.reportError(err)
+			bw.Reset(w.out)
+			atomic.AddUint64(&w.dropped, w.pending+1)
+			w.pending = 0
+			continue
+		}
+		w.pending++
+	}
+
+	// batch 会被复用，清空引用以便日志内容被回收
+	for i := range batch {
+		batch[i] = nil
+	}
+	return batch
+}
+
+// flush 刷新 bw，成功时计入 written，失败时未写出的日志计入 dropped
+func (w *AsyncWriter) flush(bw *bufio.Writer) {
+	if err := bw.Flush(); err != nil {
+		w.reportError(err)
+		// bufio.Writer 出错后不可再用，丢弃未写入的数据
+		bw.Reset(w.out)
+		atomic.AddUint64(&w.dropped, w.pending)
+	} else {
+		atomic.AddUint64(&w.written, w.pending)
+	}
+	w.pending = 0
+}
+
+func (w *AsyncWriter) reportError(err error) {
+	atomic.AddUint64(&w.errors, 1)
+	if w.OnError != nil {
+		w.OnError(err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "写入日志出错，%s\n", err)
+}
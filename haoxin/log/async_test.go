@@ -0,0 +1,135 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// 第一次写入时阻塞，直到 release 被关闭
+type gateWriter struct {
+	entered chan struct{}
+	release chan struct{}
+	once    sync.Once
+	mux     sync.Mutex
+	buf     strings.Builder
+	closed  bool
+}
+
+func newGateWriter() *gateWriter {
+	return &gateWriter{entered: make(chan struct{}), release: make(chan struct{})}
+}
+
+func (g *gateWriter) Write(p []byte) (int, error) {
+	g.once.Do(func() {
+		close(g.entered)
+		<-g.release
+	})
+
+	g.mux.Lock()
+	defer g.mux.Unlock()
+	return g.buf.Write(p)
+}
+
+func (g *gateWriter) Close() error {
+	g.closed = true
+	return nil
+}
+
+func (g *gateWriter) String() string {
+	g.mux.Lock()
+	defer g.mux.Unlock()
+	return g.buf.String()
+}
+
+func TestAsyncWriter(t *testing.T) {
+	var out strings.Builder
+	w := NewAsyncWriter(&out, AsyncOptions{BufferSize: 16})
+
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(w, "line %d\n", i)
+	}
+	w.Flush()
+
+	if strings.Count(out.String(), "\n") != 100 || !strings.HasSuffix(out.String(), "line 99\n") {
+		t.Errorf("output got = %q", out.String())
+	}
+	if stats := w.Stats(); stats.Written != 100 || stats.Dropped != 0 || stats.Buffered != 0 {
+		t.Errorf("Stats() got = %+v", stats)
+	}
+}
+
+// 写入 n 次后全部失败
+type failWriter struct {
+	n int
+}
+
+func (f *failWriter) Write(p []byte) (int, error) {
+	if f.n <= 0 {
+		return 0, errors.New("disk full")
+	}
+	f.n--
+	return len(p), nil
+}
+
+func TestAsyncWriterStatsOnError(t *testing.T) {
+	w := NewAsyncWriter(&failWriter{n: 1}, AsyncOptions{BufferSize: 16})
+	w.OnError = func(err error) {}
+
+	fmt.Fprintf(w, "line 0\n")
+	w.Flush()
+	// 写入 bufio.Writer 后刷新失败，不能计入已写出
+	for i := 1; i < 4; i++ {
+		fmt.Fprintf(w, "line %d\n", i)
+	}
+	w.Flush()
+
+	if stats := w.Stats(); stats.Written != 1 || stats.Dropped != 3 || stats.Errors != 1 {
+		t.Errorf("Stats() got = %+v", stats)
+	}
+}
+
+func TestAsyncWriterOverflow(t *testing.T) {
+	tests := []struct {
+		policy   OverflowPolicy
+		expected string
+	}{
+		{OverflowDropNewest, "e1\ne2\ne3\n"},
+		{OverflowDropOldest, "e1\ne3\ne4\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.policy), func(t *testing.T) {
+			out := newGateWriter()
+			w := NewAsyncWriter(out, AsyncOptions{BufferSize: 2, Policy: tt.policy})
+
+			// 让后台 goroutine 阻塞在底层 writer 上，之后的写入只能进入缓冲区
+			w.Write([]byte("e1\n"))
+			go w.Flush()
+			<-out.entered
+
+			for _, e := range []string{"e2\n", "e3\n", "e4\n"} {
+				w.Write([]byte(e))
+			}
+			close(out.release)
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			if out.String() != tt.expected {
+				t.Errorf("output got = %q, expected %q", out.String(), tt.expected)
+			}
+			if stats := w.Stats(); stats.Dropped != 1 {
+				t.Errorf("Stats() got = %+v", stats)
+			}
+			if !out.closed {
+				t.Errorf("Close() should close underlying writer")
+			}
+			if _, err := w.Write([]byte("e5\n")); err != ErrAsyncWriterClosed {
+				t.Errorf("Write() after close err = %v", err)
+			}
+		})
+	}
+}
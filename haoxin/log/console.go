@@ -0,0 +1,162 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// 终端颜色
+const (
+	colorRed    = 31
+	colorYellow = 33
+	colorBlue   = 36
+	colorGray   = 90
+)
+
+// ConsoleFormatter 便于阅读的控制台日志格式，用于本地开发，例如：
+//
+//	2023-01-02 15:04:05.000 INFO  查询订单 sid=4020190409104630000101 duration=12ms orderId=42
+//
+// 字段提取与 HaoxinJSONFormatter 一致
+type ConsoleFormatter struct {
+	ServiceId    string
+	ReportCaller bool
+	Colors       bool // 使用终端颜色区分日志级别
+}
+
+func (f *ConsoleFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	log, extra := newHaoxinLog(entry, f.ServiceId, f.ReportCaller)
+
+	var b bytes.Buffer
+	b.WriteString(log.Timestamp)
+	b.WriteByte(' ')
+	if f.Colors {
+		fmt.Fprintf(&b, "\x1b[%dm%-5s\x1b[0m", levelColor(entry.Level), log.Level)
+	} else {
+		fmt.Fprintf(&b, "%-5s", log.Level)
+	}
+	if log.ServiceId != "" {
+		fmt.Fprintf(&b, " [%s]", log.ServiceId)
+	}
+	b.WriteByte(' ')
+	b.WriteString(log.Message)
+
+	f.writeField(&b, "sid", log.SID)
+	f.writeField(&b, "tid", log.TID)
+	f.writeField(&b, "userid", log.UserId)
+	f.writeField(&b, "machineid", log.MachineId)
+	if log.Duration != 0 {
+		f.writeField(&b, "duration", strconv.FormatInt(log.Duration, 10)+"ms")
+	}
+	if log.Error != nil {
+		f.writeField(&b, "error", log.Error.Message)
+	}
+	if log.Caller != "" {
+		f.writeField(&b, "caller", log.Caller)
+	}
+
+	keys := make([]string, 0, len(extra))
+	for k := range extra {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		f.writeField(&b, k, fmt.Sprint(extra[k]))
+	}
+
+	b.WriteByte('\n')
+	return b.Bytes(), nil
+}
+
+func (f *ConsoleFormatter) writeField(b *bytes.Buffer, key string, value string) {
+	if value == "" {
+		return
+	}
+
+	b.WriteByte(' ')
+	if f.Colors {
+		fmt.Fprintf(b, "\x1b[%dm%s\x1b[0m=", colorGray, key)
+	} else {
+		b.WriteString(key)
+		b.WriteByte('=')
+	}
+	if strings.ContainsAny(value, " \t\r\n\"=") {
+		value = strconv.Quote(value)
+	}
+	b.WriteString(value)
+}
+
+func levelColor(level logrus.Level) int {
+	switch level {
+	case logrus.TraceLevel, logrus.DebugLevel:
+		return colorGray
+	case logrus.WarnLevel:
+		return colorYellow
+	case logrus.ErrorLevel, logrus.FatalLevel, logrus.PanicLevel:
+		return colorRed
+	}
+	return colorBlue
+}
+
+// 将日志同时输出到控制台的 hook，out 为 nil 时不输出
+type consoleHook struct {
+	out       io.Writer
+	formatter logrus.Formatter
+	mux       sync.Mutex
+}
+
+func (h *consoleHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *consoleHook) Fire(entry *logrus.Entry) error {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	if h.out == nil {
+		return nil
+	}
+
+	b, err := h.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+	_, err = h.out.Write(b)
+	return err
+}
+
+func (h *consoleHook) set(out io.Writer, formatter logrus.Formatter) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	h.out = out
+	if formatter != nil {
+		h.formatter = formatter
+	}
+}
+
+// 查找 logger 上的 consoleHook，没有时添加一个不输出的
+func consoleHookOf(logger *logrus.Logger) *consoleHook {
+	for _, hook := range logger.Hooks[logrus.PanicLevel] {
+		if h, ok := hook.(*consoleHook); ok {
+			return h
+		}
+	}
+
+	h := &consoleHook{formatter: &ConsoleFormatter{}}
+	logger.AddHook(h)
+	return h
+}
+
+// 兼容以前的行为，默认实例同时输出到 os.Stdout
+func init() {
+	consoleHookOf(logrus.StandardLogger()).set(os.Stdout, nil)
+}
@@ -0,0 +1,50 @@
+package log
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestConsoleFormatter(t *testing.T) {
+	entry := logrus.NewEntry(logrus.New())
+	entry.Time = time.Date(2023, 1, 2, 3, 4, 5, 0, time.Local)
+	entry.Level = logrus.WarnLevel
+	entry.Message = "查询订单"
+	entry.Data = logrus.Fields{
+		"sid":           "s1",
+		"duration":      12 * time.Millisecond,
+		"orderId":       42,
+		"note":          "a b",
+		logrus.ErrorKey: errors.New("timeout"),
+	}
+
+	b, err := (&ConsoleFormatter{ServiceId: "svc"}).Format(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "2023-01-02 03:04:05.000 WARN  [svc] 查询订单 sid=s1 duration=12ms error=timeout note=\"a b\" orderId=42\n"
+	if string(b) != expected {
+		t.Errorf("Format() got = %q, expected %q", b, expected)
+	}
+}
+
+func TestLoggerConsole(t *testing.T) {
+	var file, console strings.Builder
+	l := New(WithOutputs(&file), WithConsole(&console), WithConsoleFormatter(&ConsoleFormatter{}))
+
+	l.Info("hello")
+	if !strings.Contains(console.String(), "INFO  hello\n") {
+		t.Errorf("console got = %q", console.String())
+	}
+
+	l.SetConsole(nil, nil)
+	l.With(map[string]interface{}{"sid": "s1"}).Info("quiet")
+	if strings.Contains(console.String(), "quiet") || !strings.Contains(file.String(), "quiet") {
+		t.Errorf("console should be disabled, got %q", console.String())
+	}
+}
@@ -0,0 +1,151 @@
+package log
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// 有名称的日志实例，按名称调整级别
+var namedLoggers = struct {
+	loggers map[string]*Logger
+	mux     sync.RWMutex
+}{loggers: make(map[string]*Logger)}
+
+func registerLogger(name string, l *Logger) {
+	namedLoggers.mux.Lock()
+	defer namedLoggers.mux.Unlock()
+
+	namedLoggers.loggers[name] = l
+}
+
+// Named 返回通过 WithName 创建的日志实例，不存在时返回 nil
+func Named(name string) *Logger {
+	namedLoggers.mux.RLock()
+	defer namedLoggers.mux.RUnlock()
+
+	return namedLoggers.loggers[name]
+}
+
+// SetLevel 设置默认日志实例的级别
+func SetLevel(level logrus.Level) {
+	Default().SetLevel(level)
+}
+
+// SetLoggerLevel 按名称设置日志实例的级别，name 为空表示默认实例
+func SetLoggerLevel(name string, level logrus.Level) error {
+	if name == "" {
+		SetLevel(level)
+		return nil
+	}
+
+	l := Named(name)
+	if l == nil {
+		return fmt.Errorf("没有找到日志实例 %q", name)
+	}
+
+	l.SetLevel(level)
+	return nil
+}
+
+// LoggerLevel 按名称获取日志实例的级别，name 为空表示默认实例
+func LoggerLevel(name string) (logrus.Level, error) {
+	if name == "" {
+		return Default().Level(), nil
+	}
+
+	l := Named(name)
+	if l == nil {
+		return 0, fmt.Errorf("没有找到日志实例 %q", name)
+	}
+
+	return l.Level(), nil
+}
+
+// Levels 返回默认实例和所有有名称的实例的级别，默认实例的名称为空字符串
+func Levels() map[string]string {
+	namedLoggers.mux.RLock()
+	defer namedLoggers.mux.RUnlock()
+
+	levels := map[string]string{"": Default().Level().String()}
+	for name, l := range namedLoggers.loggers {
+		levels[name] = l.Level().String()
+	}
+	return levels
+}
+
+// LoggerNames 返回所有有名称的实例的名称
+func LoggerNames() []string {
+	namedLoggers.mux.RLock()
+	defer namedLoggers.mux.RUnlock()
+
+	names := make([]string, 0, len(namedLoggers.loggers))
+	for name := range namedLoggers.loggers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+type levelRequest struct {
+	Name  string `form:"name" json:"name"`
+	Level string `form:"level" json:"level" binding:"required"`
+}
+
+// LevelHandler 返回查看和调整日志级别的 gin handler，应注册在内部管理路由上
+//
+// GET 返回所有实例的级别；PUT/POST 调整级别，参数 name 为实例名称（为空表示默认实例），
+// level 取 trace、debug、info、warn、error。
+//
+// use it:
+//
+//	admin.Any("/log/level", log.LevelHandler())
+func LevelHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodGet {
+			c.JSON(http.StatusOK, gin.H{"code": 0, "msg": "ok", "data": Levels()})
+			return
+		}
+
+		var req levelRequest
+		if err := c.ShouldBind(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"code": http.StatusBadRequest, "msg": err.Error()})
+			return
+		}
+
+		level, err := logrus.ParseLevel(req.Level)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"code": http.StatusBadRequest, "msg": err.Error()})
+			return
+		}
+
+		if err := SetLoggerLevel(req.Name, level); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"code": http.StatusNotFound, "msg": err.Error()})
+			return
+		}
+
+		Warnf("日志 %q 级别调整为 %s", req.Name, level)
+		c.JSON(http.StatusOK, gin.H{"code": 0, "msg": "ok", "data": Levels()})
+	}
+}
+
+// 调高（more=true）或调低一级日志详细程度，范围 Error 到 Trace
+func stepLevel(name string, more bool) (logrus.Level, error) {
+	level, err := LoggerLevel(name)
+	if err != nil {
+		return 0, err
+	}
+
+	if more && level < logrus.TraceLevel {
+		level++
+	}
+	if !more && level > logrus.ErrorLevel {
+		level--
+	}
+
+	return level, SetLoggerLevel(name, level)
+}
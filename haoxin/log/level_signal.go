@@ -0,0 +1,46 @@
+//go:build !windows
+
+package log
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// HandleLevelSignals 监听 SIGUSR1/SIGUSR2 调整日志级别，返回的函数用于停止监听
+//
+// SIGUSR1 调高一级详细程度（例如 info -> debug），SIGUSR2 调低一级（例如 info -> warn），
+// names 为要调整的日志实例名称，为空时调整默认实例。
+func HandleLevelSignals(names ...string) func() {
+	if len(names) == 0 {
+		names = []string{""}
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1, syscall.SIGUSR2)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case sig := <-signals:
+				for _, name := range names {
+					level, err := stepLevel(name, sig == syscall.SIGUSR1)
+					if err != nil {
+						Error(err)
+						continue
+					}
+					Warnf("收到信号 %s，日志 %q 级别调整为 %s", sig, name, level)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
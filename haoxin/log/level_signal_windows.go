@@ -0,0 +1,8 @@
+//go:build windows
+
+package log
+
+// HandleLevelSignals Windows 不支持 SIGUSR1/SIGUSR2，不做任何处理
+func HandleLevelSignals(names ...string) func() {
+	return func() {}
+}
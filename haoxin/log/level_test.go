@@ -0,0 +1,57 @@
+package log
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+func TestLevelHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	l := New(WithName("level-test"), WithOutputs(&strings.Builder{}))
+
+	router := gin.New()
+	router.Any("/log/level", LevelHandler())
+
+	w := httptest.NewRecorder()
+	request := httptest.NewRequest("PUT", "/log/level?name=level-test&level=debug", nil)
+	router.ServeHTTP(w, request)
+	if w.Code != http.StatusOK || l.Level() != logrus.DebugLevel {
+		t.Errorf("PUT level got code = %d, level = %s", w.Code, l.Level())
+	}
+
+	w = httptest.NewRecorder()
+	request = httptest.NewRequest("PUT", "/log/level?name=not-exists&level=debug", nil)
+	router.ServeHTTP(w, request)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("PUT unknown logger got code = %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	request = httptest.NewRequest("GET", "/log/level", nil)
+	router.ServeHTTP(w, request)
+	if !strings.Contains(w.Body.String(), `"level-test":"debug"`) {
+		t.Errorf("GET level got body = %s", w.Body.String())
+	}
+}
+
+func TestStepLevel(t *testing.T) {
+	l := New(WithName("step-test"), WithLevel(logrus.TraceLevel))
+
+	if level, _ := stepLevel("step-test", true); level != logrus.TraceLevel {
+		t.Errorf("stepLevel() should not exceed trace, got %s", level)
+	}
+
+	l.SetLevel(logrus.ErrorLevel)
+	if level, _ := stepLevel("step-test", false); level != logrus.ErrorLevel {
+		t.Errorf("stepLevel() should not go below error, got %s", level)
+	}
+
+	if level, _ := stepLevel("step-test", true); level != logrus.WarnLevel {
+		t.Errorf("stepLevel() got %s, expected warn", level)
+	}
+}
@@ -1,19 +1,31 @@
 package log
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"path"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"time"
 
-	rotatelogs "github.com/lestrrat-go/file-rotatelogs"
+	"github.com/chengjianxi/goc/haoxin"
 	"github.com/sirupsen/logrus"
 )
 
 type HaoxinJSONFormatter struct {
 	ServiceId string
+
+	// FlattenExtra 为 true 时，HaoxinLog 之外的字段直接输出在顶层，
+	// 与 HaoxinLog 字段同名的加上 "fields." 前缀；为 false 时输出在 extra 对象中
+	FlattenExtra bool
+
+	// ReportCaller 为 true 时输出调用日志函数的文件名和行号
+	ReportCaller bool
 }
 
 type HaoxinLog struct {
@@ -26,158 +38,362 @@ type HaoxinLog struct {
 	TID       string `json:"tid,omitempty"`       // 表示操作目的方,格式:”模块ID(2位)”+”年月日时分秒(14位”+”随机数(6位)”示例:4020190409104630000101
 	UserId    string `json:"userid,omitempty"`    // 用户id
 	MachineId string `json:"machineid,omitempty"` // 用户id
+
+	Caller string                 `json:"caller,omitempty"` // 调用位置，file:line
+	Error  *ErrorDetail           `json:"error,omitempty"`  // WithError 附加的错误
+	Extra  map[string]interface{} `json:"extra,omitempty"`  // 其他字段
 }
 
-func (f *HaoxinJSONFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+// ErrorDetail 错误详情，Chain 为 errors.Unwrap 逐层展开的错误信息
+type ErrorDetail struct {
+	Message string   `json:"message"`
+	Type    string   `json:"type,omitempty"`
+	Chain   []string `json:"chain,omitempty"`
+}
+
+// HaoxinLog 中已有的字段，不会出现在 extra 中
+var haoxinLogKeys = map[string]bool{
+	"duration":      true,
+	"sid":           true,
+	"tid":           true,
+	"userid":        true,
+	"machineid":     true,
+	logrus.ErrorKey: true,
+}
+
+// HaoxinLog 的 JSON 字段名，FlattenExtra 时同名字段需要加前缀
+var haoxinLogJSONKeys = map[string]bool{
+	"timestamp": true, "level": true, "message": true, "serviceid": true, "duration": true,
+	"sid": true, "tid": true, "userid": true, "machineid": true, "caller": true, "error": true, "extra": true,
+}
+
+// NewErrorDetail 返回错误详情，err 为 nil 时返回 nil
+func NewErrorDetail(err error) *ErrorDetail {
+	if err == nil {
+		return nil
+	}
 
-	level := "INFO"
-	switch entry.Level {
-	case logrus.TraceLevel:
-		fallthrough
-	case logrus.DebugLevel:
-		level = "DEBUG"
-	case logrus.InfoLevel:
-		level = "INFO"
+	detail := &ErrorDetail{Message: err.Error(), Type: fmt.Sprintf("%T", err)}
+	for e := errors.Unwrap(err); e != nil; e = errors.Unwrap(e) {
+		detail.Chain = append(detail.Chain, e.Error())
+	}
+	return detail
+}
+
+// 本包所在目录，查找调用位置时跳过本包的非测试文件
+var packageDir = func() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Dir(file)
+}()
+
+// 查找调用日志函数的位置，跳过 logrus 和本包的栈帧
+func findCaller() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		isLogrus := strings.Contains(frame.Function, "github.com/sirupsen/logrus")
+		isSelf := filepath.Dir(frame.File) == packageDir && !strings.HasSuffix(frame.File, "_test.go")
+		if !isLogrus && !isSelf {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// 日志级别名称，取 DEBUG,INFO,WARN,ERROR
+func levelName(level logrus.Level) string {
+	switch level {
+	case logrus.TraceLevel, logrus.DebugLevel:
+		return "DEBUG"
 	case logrus.WarnLevel:
-		level = "WARN"
-	case logrus.FatalLevel:
-		level = "ERROR"
-	case logrus.ErrorLevel:
-		level = "ERROR"
+		return "WARN"
+	case logrus.ErrorLevel, logrus.FatalLevel, logrus.PanicLevel:
+		return "ERROR"
 	}
+	return "INFO"
+}
 
-	// 持续时间
-	duration, _ := entry.Data["duration"].(time.Duration)
+// newHaoxinLog 从 entry 中提取 HaoxinLog 字段，其他字段作为 extra 返回
+// HaoxinJSONFormatter 和 ConsoleFormatter 共用
+func newHaoxinLog(entry *logrus.Entry, serviceId string, reportCaller bool) (HaoxinLog, map[string]interface{}) {
+	// 持续时间，time.Duration 转为毫秒，整数视为已经是毫秒
+	var duration int64
+	switch d := entry.Data["duration"].(type) {
+	case time.Duration:
+		duration = d.Milliseconds()
+	case int64:
+		duration = d
+	case int:
+		duration = int64(d)
+	}
 	sid, _ := entry.Data["sid"].(string)
 	tid, _ := entry.Data["tid"].(string)
 	userid, _ := entry.Data["userid"].(string)
 	machineid, _ := entry.Data["machineid"].(string)
 
+	var errDetail *ErrorDetail
+	switch e := entry.Data[logrus.ErrorKey].(type) {
+	case error:
+		errDetail = NewErrorDetail(e)
+	case string:
+		errDetail = &ErrorDetail{Message: e}
+	}
+
+	var extra map[string]interface{}
+	for k, v := range entry.Data {
+		if haoxinLogKeys[k] {
+			continue
+		}
+		if extra == nil {
+			extra = make(map[string]interface{})
+		}
+		if e, ok := v.(error); ok {
+			// error 大多没有导出字段，直接序列化会得到 {}
+			v = e.Error()
+		}
+		extra[k] = v
+	}
+
 	log := HaoxinLog{
 		Timestamp: entry.Time.Format("2006-01-02 15:04:05.000"),
-		Level:     level,
+		Level:     levelName(entry.Level),
 		Message:   entry.Message,
-		ServiceId: f.ServiceId, // 服务 ID
-		Duration:  int64(duration),
+		ServiceId: serviceId, // 服务 ID
+		Duration:  duration,
 		SID:       sid,
 		TID:       tid,
 		UserId:    userid,
 		MachineId: machineid,
+		Error:     errDetail,
+	}
+	if reportCaller {
+		log.Caller = findCaller()
+	}
+
+	return log, extra
+}
+
+func (f *HaoxinJSONFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	log, extra := newHaoxinLog(entry, f.ServiceId, f.ReportCaller)
+	if f.FlattenExtra {
+		flattened := make(map[string]interface{}, len(extra))
+		for k, v := range extra {
+			if haoxinLogJSONKeys[k] {
+				k = "fields." + k
+			}
+			flattened[k] = v
+		}
+		extra = flattened
+	} else {
+		log.Extra = extra
 	}
 
 	// Note this doesn't include Time, Level and Message which are available on
 	// the Entry. Consult `godoc` on information about those fields or read the
 	// source of the official loggers.
 	serialized, err := json.Marshal(log)
+	if err != nil && log.Extra != nil {
+		// 无法序列化的字段（例如 chan、func）转为字符串后重试
+		log.Extra = stringifyValues(extra)
+		serialized, err = json.Marshal(log)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal fields to JSON, %w", err)
 	}
+
+	if f.FlattenExtra && len(extra) > 0 {
+		fields, err := json.Marshal(extra)
+		if err != nil {
+			fields, err = json.Marshal(stringifyValues(extra))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal fields to JSON, %w", err)
+		}
+		// 合并两个 JSON 对象：去掉前者的 "}" 和后者的 "{"
+		serialized = append(append(serialized[:len(serialized)-1], ','), fields[1:]...)
+	}
+
 	return append(serialized, '\n'), nil
 }
 
+func stringifyValues(fields map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		if _, err := json.Marshal(v); err != nil {
+			v = fmt.Sprintf("%+v", v)
+		}
+		result[k] = v
+	}
+	return result
+}
+
+// InitLog 配置 logrus 的全局 logger 写入按天轮转的日志文件，并作为默认日志实例
+// 日志文件保留 7 天，创建失败时输出到 os.Stderr；需要自定义轮转或处理错误时使用 InitLogWithOptions
 func InitLog(logPath string, svcName string) io.Writer {
+	writer, err := InitLogWithOptions(svcName, DefaultRotateOptions(logPath, svcName))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "创建日志文件出错，%s\n", err)
+		logrus.SetOutput(os.Stderr)
+		return os.Stderr
+	}
+
+	return writer
+}
+
+// InitLogWithOptions 配置 logrus 的全局 logger 按 opts 轮转写入日志文件，并作为默认日志实例
+func InitLogWithOptions(svcName string, opts RotateOptions) (*RotateWriter, error) {
 	formatter := &HaoxinJSONFormatter{ServiceId: svcName}
 	logrus.SetFormatter(formatter)
 	logrus.SetLevel(logrus.InfoLevel)
-	// 当天日志名 MTA.log, 每隔 1 天轮转一个新文件
-	//logpath := path.Join(c.Log.Path, "MTA.log")
-	//logrus.SetOutput(NewRotateWriter(logpath))
-	// 下面配置日志轮，每隔 1 天轮转一个新文件，保留最近 7 天的日志文件，多余的自动清理掉。
-	// github.com/lestrrat-go/file-rotatelogs
-	logpattern := path.Join(logPath, svcName+"-%Y-%m-%d.log")
-	writer, _ := rotatelogs.New(
-		logpattern,
-		rotatelogs.WithLinkName(""),
-		rotatelogs.WithMaxAge(time.Duration(7*24)*time.Hour),     // 设置文件清理前的最长保存时间，WithMaxAge 和 WithRotationCount二者只能设置一个
-		rotatelogs.WithRotationTime(time.Duration(24)*time.Hour), // 设置日志分割的时间，隔多久分割一次
-	)
+	SetDefault(NewFromLogrus(logrus.StandardLogger()))
+
+	writer, err := NewRotateWriter(opts)
+	if err != nil {
+		return nil, err
+	}
 	logrus.SetOutput(writer)
 
-	return writer
+	return writer, nil
 }
 
 func requestFields(request *http.Request) map[string]interface{} {
-	return logrus.Fields{
-		"sid":       request.Header.Get("sid"),
-		"tid":       request.Header.Get("tid"),
-		"userid":    request.Header.Get("userid"),
-		"machineid": request.Header.Get("machineid"),
-	}
+	return haoxin.MetadataFromRequest(request).Fields()
+}
+
+// 默认使用 context 中的日志实例，并附加 context 中的请求信息
+func contextLogger(ctx context.Context) *Logger {
+	return FromContext(ctx).WithContext(ctx)
+}
+
+// SetConsole 设置默认日志实例的控制台输出，为 nil 时不输出到控制台，生产环境建议关闭
+//
+// 默认实例默认同时输出到 os.Stdout；本地开发可使用带颜色的格式：
+//
+//	log.SetConsole(os.Stdout, &log.ConsoleFormatter{Colors: true})
+func SetConsole(out io.Writer, formatter logrus.Formatter) {
+	Default().SetConsole(out, formatter)
+}
+
+func Trace(args ...interface{}) {
+	Default().Trace(args...)
+}
+
+func Tracef(format string, args ...interface{}) {
+	Default().Tracef(format, args...)
+}
+
+func TraceWithFields(fields map[string]interface{}, args ...interface{}) {
+	Default().With(fields).Trace(args...)
+}
+
+func TraceLogWithRequest(request *http.Request, args ...interface{}) {
+	TraceWithFields(requestFields(request), args...)
+}
+
+func TraceContext(ctx context.Context, args ...interface{}) {
+	contextLogger(ctx).Trace(args...)
+}
+
+func Debug(args ...interface{}) {
+	Default().Debug(args...)
+}
+
+func Debugf(format string, args ...interface{}) {
+	Default().Debugf(format, args...)
+}
+
+func DebugWithFields(fields map[string]interface{}, args ...interface{}) {
+	Default().With(fields).Debug(args...)
+}
+
+func DebugLogWithRequest(request *http.Request, args ...interface{}) {
+	DebugWithFields(requestFields(request), args...)
+}
+
+func DebugContext(ctx context.Context, args ...interface{}) {
+	contextLogger(ctx).Debug(args...)
 }
 
 func Info(args ...interface{}) {
-	fmt.Println(args...)
-	logrus.Info(args...)
+	Default().Info(args...)
 }
 
 func Infof(format string, args ...interface{}) {
-	fmt.Printf(format+"\r\n", args...)
-	logrus.Infof(format, args...)
+	Default().Infof(format, args...)
 }
 
 func InfoWithFields(fields map[string]interface{}, args ...interface{}) {
-	fmt.Println(args...)
-	logrus.WithFields(fields).Info(args...)
+	Default().With(fields).Info(args...)
 }
 
 func InfoLogWithRequest(request *http.Request, args ...interface{}) {
 	InfoWithFields(requestFields(request), args...)
 }
 
+func InfoContext(ctx context.Context, args ...interface{}) {
+	contextLogger(ctx).Info(args...)
+}
+
 func Warn(args ...interface{}) {
-	fmt.Println(args...)
-	logrus.Warn(args...)
+	Default().Warn(args...)
 }
 
 func Warnf(format string, args ...interface{}) {
-	fmt.Printf(format+"\r\n", args...)
-	logrus.Warnf(format, args...)
+	Default().Warnf(format, args...)
 }
 
 func WarnWithFields(fields map[string]interface{}, args ...interface{}) {
-	fmt.Println(args...)
-	logrus.WithFields(fields).Warn(args...)
+	Default().With(fields).Warn(args...)
 }
 
 func WarnLogWithRequest(request *http.Request, args ...interface{}) {
 	WarnWithFields(requestFields(request), args...)
 }
 
+func WarnContext(ctx context.Context, args ...interface{}) {
+	contextLogger(ctx).Warn(args...)
+}
+
 func Error(args ...interface{}) {
-	fmt.Println(args...)
-	logrus.Error(args...)
+	Default().Error(args...)
 }
 
 func Errorf(format string, args ...interface{}) {
-	fmt.Printf(format+"\r\n", args...)
-	logrus.Errorf(format, args...)
-
+	Default().Errorf(format, args...)
 }
 
 func ErrorWithFields(fields map[string]interface{}, args ...interface{}) {
-	fmt.Println(args...)
-	logrus.WithFields(fields).Error(args...)
+	Default().With(fields).Error(args...)
 }
 
 func ErrorWithRequest(request *http.Request, args ...interface{}) {
 	ErrorWithFields(requestFields(request), args...)
 }
 
+func ErrorContext(ctx context.Context, args ...interface{}) {
+	contextLogger(ctx).Error(args...)
+}
+
 func Panic(args ...interface{}) {
-	fmt.Println(args...)
-	logrus.Panic(args...)
+	Default().Panic(args...)
 }
 
 func Panicf(format string, args ...interface{}) {
-	fmt.Printf(format+"\r\n", args...)
-	logrus.Panicf(format, args...)
+	Default().Panicf(format, args...)
 }
 
 func PanicWithFields(fields map[string]interface{}, args ...interface{}) {
-	fmt.Println(args...)
-	logrus.WithFields(fields).Panic(args...)
+	Default().With(fields).Panic(args...)
 }
 
 func PanicWithRequest(request *http.Request, args ...interface{}) {
 	PanicWithFields(requestFields(request), args...)
 }
+
+func PanicContext(ctx context.Context, args ...interface{}) {
+	contextLogger(ctx).Panic(args...)
+}
@@ -0,0 +1,62 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func formatEntry(t *testing.T, f *HaoxinJSONFormatter, data logrus.Fields) string {
+	entry := logrus.NewEntry(logrus.New())
+	entry.Time = time.Date(2023, 1, 2, 3, 4, 5, 0, time.Local)
+	entry.Level = logrus.InfoLevel
+	entry.Message = "hello"
+	entry.Data = data
+
+	b, err := f.Format(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestFormatExtraFields(t *testing.T) {
+	data := logrus.Fields{"sid": "s1", "orderId": 42, "message": "clash", "ch": make(chan int)}
+
+	got := formatEntry(t, &HaoxinJSONFormatter{ServiceId: "svc"}, data)
+	if !strings.Contains(got, `"sid":"s1"`) || !strings.Contains(got, `"extra":{`) ||
+		!strings.Contains(got, `"orderId":42`) || !strings.Contains(got, `"message":"clash"`) {
+		t.Errorf("Format() got = %s", got)
+	}
+
+	got = formatEntry(t, &HaoxinJSONFormatter{ServiceId: "svc", FlattenExtra: true}, data)
+	if !strings.Contains(got, `"message":"hello"`) || !strings.Contains(got, `"fields.message":"clash"`) ||
+		!strings.Contains(got, `"orderId":42`) || strings.Contains(got, `"extra"`) {
+		t.Errorf("Format() flatten got = %s", got)
+	}
+}
+
+func TestFormatError(t *testing.T) {
+	base := errors.New("connection refused")
+	err := fmt.Errorf("调用服务出错，%w", base)
+
+	got := formatEntry(t, &HaoxinJSONFormatter{}, logrus.Fields{logrus.ErrorKey: err})
+	expected := `"error":{"message":"调用服务出错，connection refused","type":"*fmt.wrapError","chain":["connection refused"]}`
+	if !strings.Contains(got, expected) {
+		t.Errorf("Format() got = %s", got)
+	}
+}
+
+func TestFormatCaller(t *testing.T) {
+	var buf strings.Builder
+	l := New(WithOutputs(&buf), WithCaller())
+	l.Info("hello")
+
+	if !strings.Contains(buf.String(), `log_test.go:`) {
+		t.Errorf("caller got = %s", buf.String())
+	}
+}
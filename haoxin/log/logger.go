@@ -0,0 +1,281 @@
+package log
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync/atomic"
+
+	"github.com/chengjianxi/goc/haoxin"
+	"github.com/chengjianxi/goc/secure"
+	"github.com/sirupsen/logrus"
+)
+
+// Logger 日志实例，通过 New 创建，互相之间不共享配置
+type Logger struct {
+	entry   *logrus.Entry
+	console *consoleHook
+	sampler *samplerRef
+}
+
+type options struct {
+	name      string
+	serviceId string
+	level     logrus.Level
+	outputs   []io.Writer
+	formatter logrus.Formatter
+	hooks     []logrus.Hook
+	caller    bool
+	sampler   *Sampler
+
+	console          io.Writer
+	consoleFormatter logrus.Formatter
+}
+
+type Option func(*options)
+
+// WithName 设置日志实例名称，有名称的实例会被注册，可按名称在运行时调整级别，见 SetLoggerLevel
+func WithName(name string) Option {
+	return func(o *options) {
+		o.name = name
+	}
+}
+
+// WithServiceId 设置服务 ID，未设置 formatter 时用于默认的 HaoxinJSONFormatter
+func WithServiceId(serviceId string) Option {
+	return func(o *options) {
+		o.serviceId = serviceId
+	}
+}
+
+// WithLevel 设置日志级别，默认 Info
+func WithLevel(level logrus.Level) Option {
+	return func(o *options) {
+		o.level = level
+	}
+}
+
+// WithOutputs 设置日志输出，多个输出同时写入，默认输出到 os.Stderr
+func WithOutputs(outputs ...io.Writer) Option {
+	return func(o *options) {
+		o.outputs = append(o.outputs, outputs...)
+	}
+}
+
+// WithFormatter 设置日志格式，默认 HaoxinJSONFormatter
+func WithFormatter(formatter logrus.Formatter) Option {
+	return func(o *options) {
+		o.formatter = formatter
+	}
+}
+
+// WithCaller 在默认的 HaoxinJSONFormatter 中输出调用位置 file:line
+func WithCaller() Option {
+	return func(o *options) {
+		o.caller = true
+	}
+}
+
+// WithConsole 同时将日志输出到控制台，例如 os.Stdout，默认使用带颜色的 ConsoleFormatter
+func WithConsole(out io.Writer) Option {
+	return func(o *options) {
+		o.console = out
+	}
+}
+
+// WithConsoleFormatter 设置控制台日志格式
+func WithConsoleFormatter(formatter logrus.Formatter) Option {
+	return func(o *options) {
+		o.consoleFormatter = formatter
+	}
+}
+
+// WithHooks 添加 logrus hook
+func WithHooks(hooks ...logrus.Hook) Option {
+	return func(o *options) {
+		o.hooks = append(o.hooks, hooks...)
+	}
+}
+
+// New 创建日志实例
+func New(opts ...Option) *Logger {
+	o := options{level: logrus.InfoLevel}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	logger := logrus.New()
+	logger.SetLevel(o.level)
+	if o.formatter != nil {
+		logger.SetFormatter(o.formatter)
+	} else {
+		logger.SetFormatter(&HaoxinJSONFormatter{ServiceId: o.serviceId, ReportCaller: o.caller})
+	}
+	switch len(o.outputs) {
+	case 0:
+		logger.SetOutput(os.Stderr)
+	case 1:
+		logger.SetOutput(o.outputs[0])
+	default:
+		logger.SetOutput(io.MultiWriter(o.outputs...))
+	}
+	for _, hook := range o.hooks {
+		logger.AddHook(hook)
+	}
+
+	console := &consoleHook{out: o.console, formatter: o.consoleFormatter}
+	if console.formatter == nil {
+		console.formatter = &ConsoleFormatter{ServiceId: o.serviceId, ReportCaller: o.caller, Colors: true}
+	}
+	logger.AddHook(console)
+
+	l := &Logger{entry: logrus.NewEntry(logger), console: console, sampler: &samplerRef{}}
+	if o.sampler != nil {
+		l.SetSampler(o.sampler)
+	}
+	if o.name != "" {
+		registerLogger(o.name, l)
+	}
+	return l
+}
+
+// NewFromLogrus 使用已有的 logrus.Logger 创建日志实例
+func NewFromLogrus(logger *logrus.Logger) *Logger {
+	return &Logger{entry: logrus.NewEntry(logger), console: consoleHookOf(logger), sampler: &samplerRef{}}
+}
+
+// Logrus 返回底层的 logrus.Logger
+func (l *Logger) Logrus() *logrus.Logger {
+	return l.entry.Logger
+}
+
+// With 返回附加了字段的日志实例，原实例不受影响，字段会按 secure.MaskFields 脱敏
+func (l *Logger) With(fields map[string]interface{}) *Logger {
+	return &Logger{entry: l.entry.WithFields(secure.MaskFields(fields)), console: l.console, sampler: l.sampler}
+}
+
+// WithContext 返回附加了 context 中请求信息的日志实例，context 中没有请求信息时返回原实例
+func (l *Logger) WithContext(ctx context.Context) *Logger {
+	m, ok := haoxin.FromContext(ctx)
+	if !ok {
+		return l
+	}
+	return l.With(m.Fields())
+}
+
+// SetConsole 设置控制台输出，为 nil 时不输出到控制台，formatter 为 nil 时保持原有格式
+func (l *Logger) SetConsole(out io.Writer, formatter logrus.Formatter) {
+	l.console.set(out, formatter)
+}
+
+// SetLevel 设置日志级别，通过 With 派生的实例共享同一级别
+func (l *Logger) SetLevel(level logrus.Level) {
+	l.entry.Logger.SetLevel(level)
+}
+
+// Level 返回当前日志级别
+func (l *Logger) Level() logrus.Level {
+	return l.entry.Logger.GetLevel()
+}
+
+func (l *Logger) Trace(args ...interface{}) {
+	if l.sample(logrus.TraceLevel, "", args) {
+		l.entry.Trace(args...)
+	}
+}
+
+func (l *Logger) Tracef(format string, args ...interface{}) {
+	if l.sample(logrus.TraceLevel, format, args) {
+		l.entry.Tracef(format, args...)
+	}
+}
+
+func (l *Logger) Debug(args ...interface{}) {
+	if l.sample(logrus.DebugLevel, "", args) {
+		l.entry.Debug(args...)
+	}
+}
+
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	if l.sample(logrus.DebugLevel, format, args) {
+		l.entry.Debugf(format, args...)
+	}
+}
+
+func (l *Logger) Info(args ...interface{}) {
+	if l.sample(logrus.InfoLevel, "", args) {
+		l.entry.Info(args...)
+	}
+}
+
+func (l *Logger) Infof(format string, args ...interface{}) {
+	if l.sample(logrus.InfoLevel, format, args) {
+		l.entry.Infof(format, args...)
+	}
+}
+
+func (l *Logger) Warn(args ...interface{}) {
+	if l.sample(logrus.WarnLevel, "", args) {
+		l.entry.Warn(args...)
+	}
+}
+
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	if l.sample(logrus.WarnLevel, format, args) {
+		l.entry.Warnf(format, args...)
+	}
+}
+
+func (l *Logger) Error(args ...interface{}) {
+	if l.sample(logrus.ErrorLevel, "", args) {
+		l.entry.Error(args...)
+	}
+}
+
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	if l.sample(logrus.ErrorLevel, format, args) {
+		l.entry.Errorf(format, args...)
+	}
+}
+
+func (l *Logger) Panic(args ...interface{}) {
+	l.entry.Panic(args...)
+}
+
+func (l *Logger) Panicf(format string, args ...interface{}) {
+	l.entry.Panicf(format, args...)
+}
+
+// 默认实例，包级别的日志函数都使用它，默认使用 logrus 的全局 logger
+var defaultLogger atomic.Value
+
+func init() {
+	defaultLogger.Store(NewFromLogrus(logrus.StandardLogger()))
+}
+
+// Default 返回默认日志实例
+func Default() *Logger {
+	return defaultLogger.Load().(*Logger)
+}
+
+// SetDefault 替换默认日志实例
+func SetDefault(l *Logger) {
+	defaultLogger.Store(l)
+}
+
+type contextKey struct{}
+
+// IntoContext 将日志实例保存到 context 中
+func IntoContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext 从 context 中获取日志实例，没有时返回默认实例
+func FromContext(ctx context.Context) *Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(contextKey{}).(*Logger); ok {
+			return l
+		}
+	}
+	return Default()
+}
@@ -0,0 +1,61 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/chengjianxi/goc/haoxin"
+	"github.com/sirupsen/logrus"
+)
+
+func TestLoggerIsolated(t *testing.T) {
+	var buf1, buf2 bytes.Buffer
+	l1 := New(WithServiceId("svc1"), WithOutputs(&buf1))
+	l2 := New(WithServiceId("svc2"), WithOutputs(&buf2), WithLevel(logrus.WarnLevel))
+
+	l1.With(map[string]interface{}{"sid": "4020190409104630000101"}).Info("hello")
+	l2.Info("ignored")
+
+	var log HaoxinLog
+	if err := json.Unmarshal(buf1.Bytes(), &log); err != nil {
+		t.Fatal(err)
+	}
+	if log.ServiceId != "svc1" || log.SID != "4020190409104630000101" || log.Message != "hello" {
+		t.Errorf("log got = %+v", log)
+	}
+	if buf2.Len() != 0 {
+		t.Errorf("info log should be filtered by warn level, got %s", buf2.String())
+	}
+}
+
+func TestLoggerContext(t *testing.T) {
+	if FromContext(context.Background()) != Default() {
+		t.Errorf("FromContext() should return default logger")
+	}
+
+	l := New()
+	if FromContext(IntoContext(context.Background(), l)) != l {
+		t.Errorf("FromContext() should return logger stored in context")
+	}
+}
+
+func TestLoggerWithContext(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(WithOutputs(&buf))
+	ctx := haoxin.NewContext(IntoContext(context.Background(), l), haoxin.Metadata{Sid: "s1", UserId: "u1"})
+
+	InfoContext(ctx, "job done")
+
+	var log HaoxinLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatal(err)
+	}
+	if log.SID != "s1" || log.UserId != "u1" || log.Message != "job done" {
+		t.Errorf("log got = %+v", log)
+	}
+	if l.WithContext(context.Background()) != l {
+		t.Errorf("WithContext() without metadata should return the same logger")
+	}
+}
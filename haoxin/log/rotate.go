@@ -0,0 +1,297 @@
+package log
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	rotatelogs "github.com/lestrrat-go/file-rotatelogs"
+)
+
+// RotateOptions 日志文件轮转配置
+//
+// 文件名为 Dir/Name-<时间>.log，时间格式由 RotationTime 决定（按天为 2006-01-02）；
+// 同一时间段内按大小轮转的文件追加序号，例如 svc-2023-01-02.log.1。
+type RotateOptions struct {
+	Dir  string // 日志目录
+	Name string // 文件名前缀，通常为服务名
+
+	RotationTime time.Duration // 按时间轮转的间隔，与 RotationSize 都为 0 时默认 24 小时
+	RotationSize int64         // 按大小轮转，单位字节，0 表示不按大小轮转
+
+	MaxAge   time.Duration // 已轮转文件的最长保留时间，与 MaxCount 只能设置一个
+	MaxCount int           // 已轮转文件的最多保留个数，与 MaxAge 只能设置一个；都为 0 时保留 7 天
+
+	Compress bool   // 使用 gzip 压缩已轮转的文件
+	LinkName string // 指向当前日志文件的软链接，为空表示不创建
+
+	Clock rotatelogs.Clock // 时钟，用于测试，默认本地时间
+}
+
+// DefaultRotateOptions 按天轮转，保留 7 天，软链接 Dir/Name-current.log 指向当前文件
+func DefaultRotateOptions(dir string, name string) RotateOptions {
+	return RotateOptions{
+		Dir:          dir,
+		Name:         name,
+		RotationTime: 24 * time.Hour,
+		MaxAge:       7 * 24 * time.Hour,
+		LinkName:     filepath.Join(dir, name+"-current.log"),
+	}
+}
+
+// RotateWriter 轮转写入日志文件，轮转后在后台压缩和清理旧文件
+type RotateWriter struct {
+	rl      *rotatelogs.RotateLogs
+	opts    RotateOptions
+	pattern *regexp.Regexp
+
+	current    string
+	mux        sync.Mutex
+	cleanupMux sync.Mutex
+	wg         sync.WaitGroup
+
+	// OnError 后台压缩、清理出错时回调，默认输出到 os.Stderr
+	OnError func(error)
+}
+
+// NewRotateWriter 按配置创建轮转写入器
+func NewRotateWriter(opts RotateOptions) (*RotateWriter, error) {
+	if opts.Name == "" {
+		return nil, errors.New("日志文件名不能为空")
+	}
+	if opts.RotationTime < 0 || opts.RotationSize < 0 || opts.MaxAge < 0 || opts.MaxCount < 0 {
+		return nil, errors.New("日志轮转配置不能为负数")
+	}
+	if opts.MaxAge > 0 && opts.MaxCount > 0 {
+		return nil, errors.New("MaxAge 和 MaxCount 只能设置一个")
+	}
+	if opts.RotationTime == 0 && opts.RotationSize == 0 {
+		opts.RotationTime = 24 * time.Hour
+	}
+	if opts.MaxAge == 0 && opts.MaxCount == 0 {
+		opts.MaxAge = 7 * 24 * time.Hour
+	}
+	if opts.Clock == nil {
+		opts.Clock = rotatelogs.Local
+	}
+
+	if err := os.MkdirAll(opts.Dir, 0755); err != nil {
+		return nil, err
+	}
+
+	// 时间格式与轮转间隔匹配，否则同一文件名会覆盖多个时间段
+	var layout string
+	switch {
+	case opts.RotationTime == 0:
+		// 只按大小轮转，文件名不带时间
+	case opts.RotationTime >= 24*time.Hour:
+		layout = "-%Y-%m-%d"
+	case opts.RotationTime >= time.Hour:
+		layout = "-%Y-%m-%d-%H"
+	default:
+		layout = "-%Y-%m-%d-%H%M"
+	}
+
+	rotateOpts := []rotatelogs.Option{
+		rotatelogs.WithClock(opts.Clock),
+		rotatelogs.WithLinkName(opts.LinkName),
+		rotatelogs.WithRotationTime(opts.RotationTime),
+		rotatelogs.WithRotationSize(opts.RotationSize),
+		// 清理由 RotateWriter 负责，rotatelogs 不会匹配带序号和压缩后的文件
+		rotatelogs.WithRotationCount(math.MaxUint32),
+	}
+
+	escaped := strings.ReplaceAll(opts.Name, "%", "%%")
+	rl, err := rotatelogs.New(filepath.Join(opts.Dir, escaped+layout+".log"), rotateOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RotateWriter{
+		rl:      rl,
+		opts:    opts,
+		pattern: regexp.MustCompile(`^` + regexp.QuoteMeta(opts.Name) + `(-\d{4}-\d{2}-\d{2}(-\d{2}(\d{2})?)?)?\.log(\.\d+)?(\.gz)?$`),
+	}, nil
+}
+
+func (w *RotateWriter) Write(p []byte) (int, error) {
+	n, err := w.rl.Write(p)
+
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	// 当前文件变化说明发生了轮转（包括首次写入），在后台压缩、清理旧文件
+	if current := w.rl.CurrentFileName(); current != w.current {
+		w.current = current
+		w.wg.Add(1)
+		go func() {
+			defer w.wg.Done()
+			w.cleanup()
+		}()
+	}
+
+	return n, err
+}
+
+// Rotate 立即轮转到新文件
+func (w *RotateWriter) Rotate() error {
+	return w.rl.Rotate()
+}
+
+// CurrentFileName 返回当前写入的文件名
+func (w *RotateWriter) CurrentFileName() string {
+	return w.rl.CurrentFileName()
+}
+
+// Close 关闭当前文件，并等待后台的压缩、清理完成
+func (w *RotateWriter) Close() error {
+	err := w.rl.Close()
+	w.wg.Wait()
+	return err
+}
+
+func (w *RotateWriter) reportError(err error) {
+	if w.OnError != nil {
+		w.OnError(err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "日志文件轮转出错，%s\n", err)
+}
+
+type rotatedFile struct {
+	path    string
+	modTime time.Time
+}
+
+// 压缩并清理当前文件以外的日志文件
+func (w *RotateWriter) cleanup() {
+	// 多次轮转可能并发触发，串行处理避免重复压缩同一个文件
+	w.cleanupMux.Lock()
+	defer w.cleanupMux.Unlock()
+
+	files, err := w.rotatedFiles()
+	if err != nil {
+		w.reportError(err)
+		return
+	}
+
+	if w.opts.Compress {
+		for i, f := range files {
+			if strings.HasSuffix(f.path, ".gz") {
+				continue
+			}
+			compressed, err := compressFile(f.path)
+			if err != nil {
+				w.reportError(err)
+				continue
+			}
+			files[i].path = compressed
+		}
+	}
+
+	// 按修改时间从新到旧排序
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.After(files[j].modTime)
+	})
+
+	cutoff := w.opts.Clock.Now().Add(-w.opts.MaxAge)
+	for i, f := range files {
+		expired := w.opts.MaxCount > 0 && i >= w.opts.MaxCount
+		if w.opts.MaxAge > 0 && f.modTime.Before(cutoff) {
+			expired = true
+		}
+		if expired {
+			if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+				w.reportError(err)
+			}
+		}
+	}
+}
+
+func (w *RotateWriter) rotatedFiles() ([]rotatedFile, error) {
+	entries, err := os.ReadDir(w.opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	// 列出文件之后再取当前文件名，期间新轮转出的文件也不会被当作旧文件处理
+	current := w.rl.CurrentFileName()
+
+	files := make([]rotatedFile, 0)
+	for _, entry := range entries {
+		path := filepath.Join(w.opts.Dir, entry.Name())
+		if !entry.Type().IsRegular() || !w.pattern.MatchString(entry.Name()) || path == current {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, rotatedFile{path: path, modTime: info.ModTime()})
+	}
+
+	return files, nil
+}
+
+// 按大小轮转时 rotatelogs 追加的序号
+var generationSuffix = regexp.MustCompile(`\.\d+$`)
+
+// 将文件压缩为 .gz 后删除原文件，保留原文件的修改时间，返回压缩后的文件名
+func compressFile(path string) (string, error) {
+	target := path + ".gz"
+	if _, err := os.Stat(target); err == nil {
+		// 重启后 rotatelogs 可能复用已压缩过的序号，换一个不冲突的序号
+		base := generationSuffix.ReplaceAllString(path, "")
+		for n := 1; ; n++ {
+			target = fmt.Sprintf("%s.%d.gz", base, n)
+			if _, err := os.Stat(target); os.IsNotExist(err) {
+				if _, err := os.Stat(fmt.Sprintf("%s.%d", base, n)); os.IsNotExist(err) {
+					break
+				}
+			}
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+
+	src, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return "", err
+	}
+
+	gz := gzip.NewWriter(dst)
+	_, err = io.Copy(gz, src)
+	if closeErr := gz.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(target)
+		return "", err
+	}
+
+	os.Chtimes(target, info.ModTime(), info.ModTime())
+	src.Close()
+	return target, os.Remove(path)
+}
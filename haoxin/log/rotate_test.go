@@ -0,0 +1,139 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	now time.Time
+	mux sync.Mutex
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Add(d time.Duration) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func listDir(t *testing.T, dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestRotateByTime(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{now: time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)}
+
+	opts := DefaultRotateOptions(dir, "svc")
+	opts.MaxAge = 0
+	opts.MaxCount = 2
+	opts.Compress = true
+	opts.Clock = clock
+	w, err := NewRotateWriter(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 4; i++ {
+		if _, err := w.Write([]byte("hello\n")); err != nil {
+			t.Fatal(err)
+		}
+		// 文件修改时间与假时钟保持一致，清理时按修改时间排序
+		os.Chtimes(w.CurrentFileName(), clock.Now(), clock.Now())
+		clock.Add(24 * time.Hour)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"svc-2023-01-02.log.gz", "svc-2023-01-03.log.gz", "svc-2023-01-04.log", "svc-current.log"}
+	got := listDir(t, dir)
+	if len(got) != len(expected) {
+		t.Fatalf("files got = %v, expected %v", got, expected)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Fatalf("files got = %v, expected %v", got, expected)
+		}
+	}
+
+	link, err := os.Readlink(filepath.Join(dir, "svc-current.log"))
+	if err != nil || filepath.Base(link) != "svc-2023-01-04.log" {
+		t.Errorf("link got = %v, %v", link, err)
+	}
+}
+
+func TestRotateBySizeAndMaxAge(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{now: time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)}
+
+	// 过期的旧文件会被清理，其他服务的文件不受影响
+	old := clock.Now().Add(-48 * time.Hour)
+	for _, name := range []string{"svc-2022-12-30.log.gz", "svc-admin-2022-12-30.log"} {
+		os.WriteFile(filepath.Join(dir, name), []byte("old\n"), 0644)
+		os.Chtimes(filepath.Join(dir, name), old, old)
+	}
+
+	w, err := NewRotateWriter(RotateOptions{
+		Dir:          dir,
+		Name:         "svc",
+		RotationTime: 24 * time.Hour,
+		RotationSize: 10,
+		MaxAge:       24 * time.Hour,
+		Clock:        clock,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		w.Write([]byte("0123456789abcdef\n"))
+		os.Chtimes(w.CurrentFileName(), clock.Now(), clock.Now())
+	}
+	w.Close()
+
+	expected := []string{"svc-2023-01-01.log", "svc-2023-01-01.log.1", "svc-2023-01-01.log.2", "svc-admin-2022-12-30.log"}
+	got := listDir(t, dir)
+	if len(got) != len(expected) {
+		t.Fatalf("files got = %v, expected %v", got, expected)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Fatalf("files got = %v, expected %v", got, expected)
+		}
+	}
+}
+
+func TestRotateOptionsError(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, err := NewRotateWriter(RotateOptions{Dir: dir, Name: "svc", MaxAge: time.Hour, MaxCount: 1}); err == nil {
+		t.Errorf("NewRotateWriter() should fail when both MaxAge and MaxCount are set")
+	}
+
+	file := filepath.Join(dir, "file")
+	os.WriteFile(file, nil, 0644)
+	if _, err := NewRotateWriter(DefaultRotateOptions(filepath.Join(file, "logs"), "svc")); err == nil {
+		t.Errorf("NewRotateWriter() should fail when dir cannot be created")
+	}
+}
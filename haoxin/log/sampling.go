@@ -0,0 +1,209 @@
+package log
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SamplingRule 相同日志的采样规则：每个 Interval 内前 First 条全部输出，之后每 Thereafter 条输出 1 条
+type SamplingRule struct {
+	First      int
+	Thereafter int           // 为 0 时超过 First 的日志全部丢弃
+	Interval   time.Duration // 默认 1 秒
+}
+
+type samplingKey struct {
+	level   logrus.Level
+	message string
+}
+
+type samplingCounter struct {
+	start      time.Time
+	count      int
+	suppressed int
+	entry      *logrus.Entry // 最近一条被抑制的日志，用于输出汇总
+}
+
+// Sampler 按日志级别和消息对重复日志采样，被抑制的条数在每个周期结束后汇总输出一条，例如：
+//
+//	已抑制 1532 条相似日志：调用 user-service 失败
+//
+// f 系列函数以 format 作为消息，参数不同的日志视为相同。Panic 级别的日志不会被采样。
+//
+// use it:
+//
+//	sampler := log.NewSampler(map[logrus.Level]log.SamplingRule{
+//		logrus.ErrorLevel: {First: 10, Thereafter: 100, Interval: time.Second},
+//	})
+//	defer sampler.Close()
+//	log.Default().SetSampler(sampler)
+type Sampler struct {
+	rules    map[logrus.Level]SamplingRule
+	counters map[samplingKey]*samplingCounter
+	mux      sync.Mutex
+	done     chan struct{}
+	once     sync.Once
+	now      func() time.Time
+}
+
+// NewSampler 创建采样器，未配置规则的级别不采样，Close 停止定时汇总
+func NewSampler(rules map[logrus.Level]SamplingRule) *Sampler {
+	s := &Sampler{
+		rules:    make(map[logrus.Level]SamplingRule, len(rules)),
+		counters: make(map[samplingKey]*samplingCounter),
+		done:     make(chan struct{}),
+		now:      time.Now,
+	}
+
+	tick := time.Duration(0)
+	for level, rule := range rules {
+		if level == logrus.PanicLevel {
+			continue
+		}
+		if rule.Interval <= 0 {
+			rule.Interval = time.Second
+		}
+		if tick == 0 || rule.Interval < tick {
+			tick = rule.Interval
+		}
+		s.rules[level] = rule
+	}
+
+	if tick > 0 {
+		go s.run(tick)
+	}
+	return s
+}
+
+// Close 输出未汇总的抑制条数并停止定时汇总
+func (s *Sampler) Close() {
+	s.once.Do(func() {
+		close(s.done)
+		s.flush(true)
+	})
+}
+
+// 返回日志是否需要输出
+func (s *Sampler) allow(entry *logrus.Entry, level logrus.Level, message string) bool {
+	rule, ok := s.rules[level]
+	if !ok {
+		return true
+	}
+
+	key := samplingKey{level: level, message: message}
+	now := s.now()
+
+	s.mux.Lock()
+	c := s.counters[key]
+	if c == nil {
+		c = &samplingCounter{start: now}
+		s.counters[key] = c
+	}
+
+	var summary *logrus.Entry
+	var suppressed int
+	if now.Sub(c.start) >= rule.Interval {
+		summary, suppressed = c.entry, c.suppressed
+		*c = samplingCounter{start: now}
+	}
+
+	c.count++
+	allowed := c.count <= rule.First || (rule.Thereafter > 0 && (c.count-rule.First)%rule.Thereafter == 0)
+	if !allowed {
+		c.suppressed++
+		c.entry = entry
+	}
+	s.mux.Unlock()
+
+	if suppressed > 0 {
+		logSuppressed(summary, key, suppressed)
+	}
+	return allowed
+}
+
+func (s *Sampler) run(tick time.Duration) {
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.flush(false)
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// 输出已结束周期的抑制条数，并清理不再出现的消息，all 为 true 时输出全部
+func (s *Sampler) flush(all bool) {
+	type summary struct {
+		entry      *logrus.Entry
+		key        samplingKey
+		suppressed int
+	}
+
+	now := s.now()
+	var summaries []summary
+
+	s.mux.Lock()
+	for key, c := range s.counters {
+		if !all && now.Sub(c.start) < s.rules[key.level].Interval {
+			continue
+		}
+		if c.suppressed > 0 {
+			summaries = append(summaries, summary{entry: c.entry, key: key, suppressed: c.suppressed})
+		}
+		delete(s.counters, key)
+	}
+	s.mux.Unlock()
+
+	for _, sum := range summaries {
+		logSuppressed(sum.entry, sum.key, sum.suppressed)
+	}
+}
+
+// 直接通过 logrus 输出，不再经过采样
+func logSuppressed(entry *logrus.Entry, key samplingKey, suppressed int) {
+	entry.WithField("suppressed", suppressed).Logf(key.level, "已抑制 %d 条相似日志：%s", suppressed, key.message)
+}
+
+type samplerRef struct {
+	v atomic.Value
+}
+
+func (r *samplerRef) load() *Sampler {
+	s, _ := r.v.Load().(*Sampler)
+	return s
+}
+
+// WithSampler 对重复日志采样，见 Sampler
+func WithSampler(sampler *Sampler) Option {
+	return func(o *options) {
+		o.sampler = sampler
+	}
+}
+
+// SetSampler 设置采样器，为 nil 时不采样，通过 With 派生的实例共享同一采样器
+func (l *Logger) SetSampler(sampler *Sampler) {
+	l.sampler.v.Store(sampler)
+}
+
+// 返回日志是否需要输出，未启用的级别不计入采样，format 为空时以 args 拼接的消息采样
+func (l *Logger) sample(level logrus.Level, format string, args []interface{}) bool {
+	s := l.sampler.load()
+	if s == nil {
+		return true
+	}
+	if !l.entry.Logger.IsLevelEnabled(level) {
+		return false
+	}
+	if format == "" {
+		format = fmt.Sprint(args...)
+	}
+	return s.allow(l.entry, level, format)
+}
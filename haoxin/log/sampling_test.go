@@ -0,0 +1,79 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestSampler(t *testing.T) {
+	now := time.Date(2023, 1, 2, 3, 4, 5, 0, time.Local)
+	sampler := NewSampler(map[logrus.Level]SamplingRule{
+		logrus.ErrorLevel: {First: 2, Thereafter: 3, Interval: time.Hour},
+	})
+	sampler.now = func() time.Time { return now }
+	defer sampler.Close()
+
+	var buf bytes.Buffer
+	l := New(WithOutputs(&buf), WithFormatter(&logrus.TextFormatter{DisableTimestamp: true}), WithSampler(sampler))
+
+	// 第 1、2 条全部输出，之后每 3 条输出 1 条：第 5、8 条
+	for i := 0; i < 10; i++ {
+		l.Errorf("调用 %s 失败", "user-service")
+		l.Info("not sampled")
+	}
+	if got := strings.Count(buf.String(), "调用 user-service 失败"); got != 4 {
+		t.Errorf("sampled count got = %d, output %s", got, buf.String())
+	}
+	if got := strings.Count(buf.String(), "not sampled"); got != 10 {
+		t.Errorf("info count got = %d", got)
+	}
+
+	// 新周期的第一条日志之前输出上一周期的汇总
+	buf.Reset()
+	now = now.Add(time.Hour)
+	l.With(map[string]interface{}{"sid": "s1"}).Errorf("调用 %s 失败", "order-service")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], "已抑制 6 条相似日志：调用 %s 失败") ||
+		!strings.Contains(lines[0], "suppressed=6") || !strings.Contains(lines[1], "order-service") {
+		t.Errorf("output got = %q", lines)
+	}
+}
+
+func TestSamplerFlush(t *testing.T) {
+	now := time.Date(2023, 1, 2, 3, 4, 5, 0, time.Local)
+	sampler := NewSampler(map[logrus.Level]SamplingRule{
+		logrus.WarnLevel: {First: 1, Interval: time.Hour},
+	})
+	sampler.now = func() time.Time { return now }
+	defer sampler.Close()
+
+	var buf bytes.Buffer
+	l := New(WithOutputs(&buf), WithFormatter(&logrus.TextFormatter{DisableTimestamp: true}), WithLevel(logrus.WarnLevel))
+	l.SetSampler(sampler)
+	for i := 0; i < 5; i++ {
+		l.Warn("disk full")
+		l.Debug("disabled")
+	}
+
+	// 周期未结束时不汇总
+	sampler.flush(false)
+	if strings.Contains(buf.String(), "已抑制") {
+		t.Errorf("output got = %s", buf.String())
+	}
+
+	now = now.Add(time.Hour)
+	sampler.flush(false)
+	if !strings.Contains(buf.String(), "已抑制 4 条相似日志：disk full") || len(sampler.counters) != 0 {
+		t.Errorf("output got = %s", buf.String())
+	}
+
+	l.SetSampler(nil)
+	l.Warn("disk full")
+	if strings.Count(buf.String(), "msg=\"disk full\"") != 2 {
+		t.Errorf("output got = %s", buf.String())
+	}
+}
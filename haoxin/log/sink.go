@@ -0,0 +1,474 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var errSinkReconnectWait = errors.New("等待重新连接")
+
+// SinkStats 日志发送统计
+type SinkStats struct {
+	Sent    uint64 // 已发送的条数
+	Dropped uint64 // 队列满或发送失败被丢弃的条数
+	Errors  uint64 // 发送出错的次数
+}
+
+// 日志发送 hook 的公共部分：Fire 格式化后放入队列，由后台 goroutine 发送，不阻塞业务日志
+type sink struct {
+	levels  []logrus.Level
+	format  func(entry *logrus.Entry) ([]byte, error)
+	entries chan []byte
+	closed  bool
+	mux     sync.RWMutex
+	stopped chan struct{}
+	onError func(error)
+	failing int32 // 连续出错时只报告第一次
+
+	sent    uint64
+	dropped uint64
+	errors  uint64
+}
+
+func newSink(levels []logrus.Level, queueSize int, onError func(error)) *sink {
+	if len(levels) == 0 {
+		levels = logrus.AllLevels
+	}
+	if queueSize <= 0 {
+		queueSize = 4096
+	}
+
+	return &sink{
+		levels:  levels,
+		entries: make(chan []byte, queueSize),
+		stopped: make(chan struct{}),
+		onError: onError,
+	}
+}
+
+func (s *sink) Levels() []logrus.Level {
+	return s.levels
+}
+
+func (s *sink) Fire(entry *logrus.Entry) error {
+	b, err := s.format(entry)
+	if err != nil {
+		return err
+	}
+
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	if s.closed {
+		atomic.AddUint64(&s.dropped, 1)
+		return nil
+	}
+
+	select {
+	case s.entries <- b:
+	default:
+		atomic.AddUint64(&s.dropped, 1)
+	}
+	return nil
+}
+
+// Close 发送完队列中的日志后停止
+func (s *sink) Close() error {
+	s.mux.Lock()
+	if s.closed {
+		s.mux.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.entries)
+	s.mux.Unlock()
+
+	<-s.stopped
+	return nil
+}
+
+// Stats 返回发送统计
+func (s *sink) Stats() SinkStats {
+	return SinkStats{
+		Sent:    atomic.LoadUint64(&s.sent),
+		Dropped: atomic.LoadUint64(&s.dropped),
+		Errors:  atomic.LoadUint64(&s.errors),
+	}
+}
+
+func (s *sink) succeeded(n int) {
+	atomic.AddUint64(&s.sent, uint64(n))
+	atomic.StoreInt32(&s.failing, 0)
+}
+
+func (s *sink) failed(n int, err error) {
+	atomic.AddUint64(&s.dropped, uint64(n))
+	atomic.AddUint64(&s.errors, 1)
+	if !atomic.CompareAndSwapInt32(&s.failing, 0, 1) {
+		return
+	}
+	if s.onError != nil {
+		s.onError(err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "发送日志出错，%s\n", err)
+}
+
+// 断线后自动重连的连接，只在 sink 的后台 goroutine 中使用
+type reconnectConn struct {
+	network       string
+	addr          string
+	dialTimeout   time.Duration
+	retryInterval time.Duration
+	conn          net.Conn
+	lastFail      time.Time
+}
+
+func (r *reconnectConn) dial() error {
+	if !r.lastFail.IsZero() && time.Since(r.lastFail) < r.retryInterval {
+		return errSinkReconnectWait
+	}
+
+	conn, err := net.DialTimeout(r.network, r.addr, r.dialTimeout)
+	if err != nil {
+		r.lastFail = time.Now()
+		return err
+	}
+
+	r.conn = conn
+	r.lastFail = time.Time{}
+	return nil
+}
+
+func (r *reconnectConn) Write(b []byte) (int, error) {
+	var err error
+	// 连接可能已被对端关闭，写入失败时重连再试一次
+	for attempt := 0; attempt < 2; attempt++ {
+		if r.conn == nil {
+			if err = r.dial(); err != nil {
+				return 0, err
+			}
+		}
+
+		r.conn.SetWriteDeadline(time.Now().Add(r.dialTimeout))
+		var n int
+		if n, err = r.conn.Write(b); err == nil {
+			return n, nil
+		}
+		r.conn.Close()
+		r.conn = nil
+	}
+	return 0, err
+}
+
+func (r *reconnectConn) Close() error {
+	if r.conn == nil {
+		return nil
+	}
+	err := r.conn.Close()
+	r.conn = nil
+	return err
+}
+
+// NetworkSinkConfig TCP/UDP 发送配置
+type NetworkSinkConfig struct {
+	Network   string // tcp 或 udp
+	Addr      string // 例如 127.0.0.1:5170
+	ServiceId string // 默认格式中的服务 ID
+
+	Levels            []logrus.Level   // 发送的日志级别，默认全部
+	Formatter         logrus.Formatter // 默认 HaoxinJSONFormatter
+	QueueSize         int              // 待发送队列长度，默认 4096，满了丢弃
+	DialTimeout       time.Duration    // 连接和写入超时，默认 3 秒
+	ReconnectInterval time.Duration    // 连接失败后重连的最小间隔，默认 1 秒
+	OnError           func(error)      // 发送出错时回调，默认输出到 os.Stderr
+}
+
+// NetworkHook 通过 TCP/UDP 发送换行分隔的 JSON 日志，断线自动重连
+//
+// use it:
+//
+//	hook := log.NewNetworkHook(log.NetworkSinkConfig{Network: "tcp", Addr: "logs:5170"})
+//	logger := log.New(log.WithServiceId(svcName), log.WithHooks(hook))
+//	defer hook.Close()
+type NetworkHook struct {
+	*sink
+}
+
+func NewNetworkHook(cfg NetworkSinkConfig) *NetworkHook {
+	if cfg.Formatter == nil {
+		cfg.Formatter = &HaoxinJSONFormatter{ServiceId: cfg.ServiceId}
+	}
+
+	s := newSink(cfg.Levels, cfg.QueueSize, cfg.OnError)
+	s.format = cfg.Formatter.Format
+	go runConnSink(s, newReconnectConn(cfg.Network, cfg.Addr, cfg.DialTimeout, cfg.ReconnectInterval))
+
+	return &NetworkHook{sink: s}
+}
+
+func newReconnectConn(network string, addr string, dialTimeout time.Duration, reconnectInterval time.Duration) *reconnectConn {
+	if dialTimeout <= 0 {
+		dialTimeout = 3 * time.Second
+	}
+	if reconnectInterval <= 0 {
+		reconnectInterval = time.Second
+	}
+	return &reconnectConn{network: network, addr: addr, dialTimeout: dialTimeout, retryInterval: reconnectInterval}
+}
+
+func runConnSink(s *sink, conn *reconnectConn) {
+	defer close(s.stopped)
+	defer conn.Close()
+
+	for b := range s.entries {
+		if _, err := conn.Write(b); err != nil {
+			s.failed(1, err)
+			continue
+		}
+		s.succeeded(1)
+	}
+}
+
+// syslog facility
+const (
+	FacilityUser   = 1
+	FacilityLocal0 = 16
+)
+
+// SyslogSinkConfig RFC5424 syslog 发送配置
+type SyslogSinkConfig struct {
+	Network  string // tcp 或 udp，tcp 使用 RFC6587 octet counting 分帧
+	Addr     string // 例如 127.0.0.1:514
+	Facility int    // 默认 FacilityLocal0
+	AppName  string // 同时作为默认格式中的服务 ID
+	Hostname string // 默认 os.Hostname()
+
+	Levels            []logrus.Level
+	Formatter         logrus.Formatter // MSG 部分的格式，默认 HaoxinJSONFormatter
+	QueueSize         int
+	DialTimeout       time.Duration
+	ReconnectInterval time.Duration
+	OnError           func(error)
+}
+
+// SyslogHook 以 RFC5424 格式发送日志到 syslog，MSG 部分为 HaoxinLog JSON
+type SyslogHook struct {
+	*sink
+}
+
+func NewSyslogHook(cfg SyslogSinkConfig) *SyslogHook {
+	if cfg.Formatter == nil {
+		cfg.Formatter = &HaoxinJSONFormatter{ServiceId: cfg.AppName}
+	}
+	if cfg.Facility == 0 {
+		cfg.Facility = FacilityLocal0
+	}
+	if cfg.Hostname == "" {
+		cfg.Hostname, _ = os.Hostname()
+	}
+
+	s := newSink(cfg.Levels, cfg.QueueSize, cfg.OnError)
+	s.format = func(entry *logrus.Entry) ([]byte, error) {
+		msg, err := cfg.Formatter.Format(entry)
+		if err != nil {
+			return nil, err
+		}
+		return formatSyslog(entry, cfg, bytes.TrimRight(msg, "\n")), nil
+	}
+	go runConnSink(s, newReconnectConn(cfg.Network, cfg.Addr, cfg.DialTimeout, cfg.ReconnectInterval))
+
+	return &SyslogHook{sink: s}
+}
+
+// <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+func formatSyslog(entry *logrus.Entry, cfg SyslogSinkConfig, msg []byte) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "<%d>1 %s %s %s %d - - ",
+		cfg.Facility*8+syslogSeverity(entry.Level),
+		entry.Time.Format("2006-01-02T15:04:05.000000Z07:00"),
+		syslogHeaderField(cfg.Hostname),
+		syslogHeaderField(cfg.AppName),
+		os.Getpid())
+	b.Write(msg)
+
+	if cfg.Network == "udp" {
+		return b.Bytes()
+	}
+	// TCP 使用 octet counting：MSG-LEN SP SYSLOG-MSG
+	return append([]byte(strconv.Itoa(b.Len())+" "), b.Bytes()...)
+}
+
+// 头部字段为空时使用 NILVALUE，不能包含空格
+func syslogHeaderField(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return strings.ReplaceAll(value, " ", "_")
+}
+
+func syslogSeverity(level logrus.Level) int {
+	switch level {
+	case logrus.PanicLevel:
+		return 1 // alert
+	case logrus.FatalLevel:
+		return 2 // critical
+	case logrus.ErrorLevel:
+		return 3
+	case logrus.WarnLevel:
+		return 4
+	case logrus.InfoLevel:
+		return 6
+	}
+	return 7 // debug
+}
+
+// HTTPSinkConfig HTTP 批量发送配置
+type HTTPSinkConfig struct {
+	URL       string
+	Headers   map[string]string // 例如鉴权 Header
+	ServiceId string            // 默认格式中的服务 ID
+
+	BatchSize     int           // 每批最多条数，默认 100
+	FlushInterval time.Duration // 不满一批时的发送间隔，默认 1 秒
+	MaxRetries    int           // 失败重试次数，默认 3
+	RetryBackoff  time.Duration // 首次重试等待时间，之后每次翻倍，默认 500 毫秒
+	Timeout       time.Duration // 请求超时，默认 5 秒
+	Client        *http.Client  // 默认使用 Timeout 创建
+
+	Levels    []logrus.Level
+	Formatter logrus.Formatter
+	QueueSize int
+	OnError   func(error)
+}
+
+// HTTPHook 批量 POST 换行分隔的 JSON 日志（Content-Type: application/x-ndjson），
+// 网络错误、429 和 5xx 会按指数退避重试
+type HTTPHook struct {
+	*sink
+	cfg HTTPSinkConfig
+}
+
+func NewHTTPHook(cfg HTTPSinkConfig) *HTTPHook {
+	if cfg.Formatter == nil {
+		cfg.Formatter = &HaoxinJSONFormatter{ServiceId: cfg.ServiceId}
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Second
+	}
+	if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 0
+	} else if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 3
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = 500 * time.Millisecond
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: cfg.Timeout}
+	}
+
+	s := newSink(cfg.Levels, cfg.QueueSize, cfg.OnError)
+	s.format = cfg.Formatter.Format
+	h := &HTTPHook{sink: s, cfg: cfg}
+	go h.run()
+
+	return h
+}
+
+func (h *HTTPHook) run() {
+	defer close(h.stopped)
+
+	batch := make([][]byte, 0, h.cfg.BatchSize)
+	ticker := time.NewTicker(h.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	send := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := h.post(bytes.Join(batch, nil)); err != nil {
+			h.failed(len(batch), err)
+		} else {
+			h.succeeded(len(batch))
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case b, ok := <-h.entries:
+			if !ok {
+				send()
+				return
+			}
+			batch = append(batch, b)
+			if len(batch) >= h.cfg.BatchSize {
+				send()
+			}
+		case <-ticker.C:
+			send()
+		}
+	}
+}
+
+func (h *HTTPHook) post(body []byte) error {
+	var err error
+	backoff := h.cfg.RetryBackoff
+	for attempt := 0; attempt <= h.cfg.MaxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		var retry bool
+		if retry, err = h.postOnce(body); err == nil || !retry {
+			return err
+		}
+	}
+	return err
+}
+
+// 返回是否需要重试
+func (h *HTTPHook) postOnce(body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, h.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	for k, v := range h.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := h.cfg.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	// 读完响应才能复用连接
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	err = fmt.Errorf("日志服务返回 %s", resp.Status)
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, err
+}
@@ -0,0 +1,230 @@
+package log
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNetworkHookTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	lines := make(chan string, 16)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				scanner := bufio.NewScanner(conn)
+				// 收到第一条后关闭连接，验证客户端重连
+				if scanner.Scan() {
+					lines <- scanner.Text()
+				}
+				conn.Close()
+			}()
+		}
+	}()
+
+	hook := NewNetworkHook(NetworkSinkConfig{Network: "tcp", Addr: ln.Addr().String(), ServiceId: "svc", ReconnectInterval: 10 * time.Millisecond})
+	defer hook.Close()
+	l := New(WithOutputs(io.Discard), WithHooks(hook))
+
+	l.Info("first")
+	if got := <-lines; !strings.Contains(got, `"message":"first"`) || !strings.Contains(got, `"serviceid":"svc"`) {
+		t.Errorf("received got = %s", got)
+	}
+
+	// 对端关闭后的第一次写入可能仍然成功，持续写入直到在新连接上收到
+	deadline := time.After(5 * time.Second)
+	for {
+		l.Info("second")
+		select {
+		case got := <-lines:
+			if !strings.Contains(got, `"message":"second"`) {
+				t.Errorf("received got = %s", got)
+			}
+			return
+		case <-deadline:
+			t.Fatalf("no message after reconnect, stats %+v", hook.Stats())
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+}
+
+func TestSyslogHookUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	hook := NewSyslogHook(SyslogSinkConfig{Network: "udp", Addr: conn.LocalAddr().String(), AppName: "svc", Hostname: "host 1"})
+	l := New(WithOutputs(io.Discard), WithHooks(hook))
+	l.Warn("disk full")
+	hook.Close()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 4096)
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// local0(16)*8 + warning(4) = 132
+	pattern := regexp.MustCompile(`^<132>1 \S+ host_1 svc \d+ - - \{.*"message":"disk full".*\}$`)
+	if !pattern.Match(buf[:n]) {
+		t.Errorf("syslog message got = %s", buf[:n])
+	}
+	if stats := hook.Stats(); stats.Sent != 1 {
+		t.Errorf("Stats() got = %+v", stats)
+	}
+}
+
+func TestSyslogOctetCounting(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		b, _ := io.ReadAll(conn)
+		received <- string(b)
+	}()
+
+	hook := NewSyslogHook(SyslogSinkConfig{Network: "tcp", Addr: ln.Addr().String(), AppName: "svc"})
+	l := New(WithOutputs(io.Discard), WithHooks(hook))
+	l.Error("one")
+	l.Error("two")
+	hook.Close()
+
+	data := <-received
+	for _, message := range []string{"one", "two"} {
+		var length int
+		i := strings.IndexByte(data, ' ')
+		for _, c := range data[:i] {
+			length = length*10 + int(c-'0')
+		}
+		frame := data[i+1 : i+1+length]
+		if !strings.HasPrefix(frame, "<131>1 ") || !strings.HasSuffix(frame, "}") || !strings.Contains(frame, `"message":"`+message+`"`) {
+			t.Errorf("frame got = %s", frame)
+		}
+		data = data[i+1+length:]
+	}
+	if data != "" {
+		t.Errorf("trailing data = %q", data)
+	}
+}
+
+func TestHTTPHookBatchRetry(t *testing.T) {
+	var requests int32
+	var mux sync.Mutex
+	var bodies []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 第一次请求失败，验证重试
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.Header.Get("Content-Type") != "application/x-ndjson" || r.Header.Get("Authorization") != "token" {
+			t.Errorf("headers got = %v", r.Header)
+		}
+		b, _ := io.ReadAll(r.Body)
+		mux.Lock()
+		bodies = append(bodies, string(b))
+		mux.Unlock()
+	}))
+	defer server.Close()
+
+	hook := NewHTTPHook(HTTPSinkConfig{
+		URL:           server.URL,
+		Headers:       map[string]string{"Authorization": "token"},
+		BatchSize:     3,
+		FlushInterval: time.Hour,
+		RetryBackoff:  time.Millisecond,
+	})
+	l := New(WithOutputs(io.Discard), WithHooks(hook))
+	for i := 0; i < 4; i++ {
+		l.Info("batch")
+	}
+	hook.Close()
+
+	// 满 3 条发送一批，关闭时发送剩余 1 条
+	if len(bodies) != 2 || strings.Count(bodies[0], "\n") != 3 || strings.Count(bodies[1], "\n") != 1 {
+		t.Errorf("bodies got = %q", bodies)
+	}
+	if stats := hook.Stats(); stats.Sent != 4 || stats.Dropped != 0 {
+		t.Errorf("Stats() got = %+v", stats)
+	}
+}
+
+func TestHTTPHookKeepAlive(t *testing.T) {
+	var conns int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		// 响应超过 Transport 的读缓冲，不读完时连接不能复用
+		w.Write([]byte(strings.Repeat("ok", 16<<10)))
+	}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	hook := NewHTTPHook(HTTPSinkConfig{URL: server.URL, BatchSize: 1, FlushInterval: time.Hour})
+	l := New(WithOutputs(io.Discard), WithHooks(hook))
+	for i := 0; i < 3; i++ {
+		l.Info("keep-alive")
+	}
+	hook.Close()
+
+	// 每批发送后读完响应，连接可以复用
+	if stats := hook.Stats(); atomic.LoadInt32(&conns) != 1 || stats.Sent != 3 {
+		t.Errorf("connections got = %d, Stats() = %+v", conns, stats)
+	}
+}
+
+func TestHTTPHookClientError(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	var reported error
+	hook := NewHTTPHook(HTTPSinkConfig{URL: server.URL, RetryBackoff: time.Millisecond, OnError: func(err error) { reported = err }})
+	New(WithOutputs(io.Discard), WithHooks(hook)).Info("rejected")
+	hook.Close()
+
+	// 4xx 不重试
+	if requests != 1 || reported == nil {
+		t.Errorf("requests got = %d, err = %v", requests, reported)
+	}
+	if stats := hook.Stats(); stats.Dropped != 1 || stats.Errors != 1 {
+		t.Errorf("Stats() got = %+v", stats)
+	}
+}
@@ -0,0 +1,72 @@
+package log
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Timed 开始计时，调用返回的函数时输出一条带 duration 的 Info 日志
+//
+// use it:
+//
+//	stop := log.Timed(map[string]interface{}{"sid": sid}, "查询订单")
+//	defer stop()
+func (l *Logger) Timed(fields map[string]interface{}, msg string) func() {
+	start := time.Now()
+	return func() {
+		merged := make(map[string]interface{}, len(fields)+1)
+		for k, v := range fields {
+			merged[k] = v
+		}
+		merged["duration"] = time.Since(start)
+		l.With(merged).Info(msg)
+	}
+}
+
+// Timed 使用默认日志实例计时，见 Logger.Timed
+func Timed(fields map[string]interface{}, msg string) func() {
+	return Default().Timed(fields, msg)
+}
+
+// AccessLog 返回记录访问日志的 gin 中间件，l 为 nil 时使用默认日志实例
+//
+// 每个请求输出一条 HaoxinLog 格式的日志，包含 duration、sid、tid、userid、machineid，
+// 以及 extra 中的 status、method、path、clientip；5xx 记为 ERROR，4xx 记为 WARN。
+func AccessLog(l *Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		path := c.Request.URL.Path
+
+		c.Next()
+
+		status := c.Writer.Status()
+		fields := requestFields(c.Request)
+		fields["duration"] = time.Since(start)
+		fields["status"] = status
+		fields["method"] = c.Request.Method
+		fields["path"] = path
+		fields["clientip"] = c.ClientIP()
+		if len(c.Errors) > 0 {
+			fields["errors"] = c.Errors.String()
+		}
+
+		logger := l
+		if logger == nil {
+			logger = Default()
+		}
+		logger = logger.With(fields)
+
+		msg := fmt.Sprintf("%s %s %d", c.Request.Method, path, status)
+		switch {
+		case status >= http.StatusInternalServerError:
+			logger.Error(msg)
+		case status >= http.StatusBadRequest:
+			logger.Warn(msg)
+		default:
+			logger.Info(msg)
+		}
+	}
+}
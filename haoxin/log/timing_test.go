@@ -0,0 +1,55 @@
+package log
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestTimed(t *testing.T) {
+	var buf strings.Builder
+	l := New(WithOutputs(&buf))
+
+	stop := l.Timed(map[string]interface{}{"sid": "s1"}, "查询订单")
+	time.Sleep(20 * time.Millisecond)
+	stop()
+
+	var log HaoxinLog
+	if err := json.Unmarshal([]byte(buf.String()), &log); err != nil {
+		t.Fatal(err)
+	}
+	// duration 以毫秒计数
+	if log.Duration < 20 || log.Duration > 1000 || log.SID != "s1" {
+		t.Errorf("Timed() got = %+v", log)
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var buf strings.Builder
+	l := New(WithOutputs(&buf))
+
+	router := gin.New()
+	router.Use(AccessLog(l))
+	router.GET("/orders/:id", func(c *gin.Context) {
+		c.String(500, "error")
+	})
+
+	request := httptest.NewRequest("GET", "/orders/1", nil)
+	request.Header.Set("sid", "s1")
+	request.Header.Set("userid", "10001")
+	router.ServeHTTP(httptest.NewRecorder(), request)
+
+	var log HaoxinLog
+	if err := json.Unmarshal([]byte(buf.String()), &log); err != nil {
+		t.Fatal(err)
+	}
+	if log.Level != "ERROR" || log.SID != "s1" || log.UserId != "10001" ||
+		log.Extra["status"] != float64(500) || log.Extra["path"] != "/orders/1" {
+		t.Errorf("AccessLog() got = %+v", log)
+	}
+}
@@ -0,0 +1,123 @@
+package haoxin
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 服务之间传递请求信息的 Header
+const (
+	HeaderSid       = "sid"
+	HeaderTid       = "tid"
+	HeaderUserId    = "userid"
+	HeaderMachineId = "machineid"
+	HeaderTimeout   = "timeout" // 调用方剩余的超时时间，毫秒
+)
+
+// Metadata 请求信息，随 context.Context 在日志和服务调用之间传递，
+// 没有 http 请求的后台任务也可以通过 NewContext 携带
+type Metadata struct {
+	Sid       string
+	Tid       string
+	UserId    string
+	MachineId string
+}
+
+// MetadataFromRequest 从请求 Header 中读取请求信息
+func MetadataFromRequest(request *http.Request) Metadata {
+	return Metadata{
+		Sid:       request.Header.Get(HeaderSid),
+		Tid:       request.Header.Get(HeaderTid),
+		UserId:    request.Header.Get(HeaderUserId),
+		MachineId: request.Header.Get(HeaderMachineId),
+	}
+}
+
+// SetHeader 将请求信息写入 Header
+func (m Metadata) SetHeader(header http.Header) {
+	header.Set(HeaderSid, m.Sid)
+	header.Set(HeaderTid, m.Tid)
+	header.Set(HeaderUserId, m.UserId)
+	header.Set(HeaderMachineId, m.MachineId)
+}
+
+// Fields 返回日志字段
+func (m Metadata) Fields() map[string]interface{} {
+	return map[string]interface{}{
+		HeaderSid:       m.Sid,
+		HeaderTid:       m.Tid,
+		HeaderUserId:    m.UserId,
+		HeaderMachineId: m.MachineId,
+	}
+}
+
+type metadataKey struct{}
+
+// NewContext 返回携带请求信息的 context
+func NewContext(ctx context.Context, m Metadata) context.Context {
+	return context.WithValue(ctx, metadataKey{}, m)
+}
+
+// FromContext 从 context 中获取请求信息，ctx 可以是经过 Middleware 的 *gin.Context
+func FromContext(ctx context.Context) (Metadata, bool) {
+	if ctx == nil {
+		return Metadata{}, false
+	}
+	// gin.Context 默认不会从 Request 的 context 中取值
+	if c, ok := ctx.(*gin.Context); ok && c.Request != nil {
+		ctx = c.Request.Context()
+	}
+
+	m, ok := ctx.Value(metadataKey{}).(Metadata)
+	return m, ok
+}
+
+// Middleware 返回 gin 中间件，从请求 Header 中读取请求信息保存到请求的 context 中，
+// 没有 sid 时使用 NewSid(moduleId) 生成并写回请求 Header，模块 ID 不正确时 panic
+//
+// use it:
+//
+//	router.Use(haoxin.Middleware("40"))
+//	...
+//	log.InfoContext(c, "查询订单")
+//	rpc.RpcContext(c, http.MethodGet, addr, "/orders", nil, nil)
+func Middleware(moduleId string) gin.HandlerFunc {
+	if err := ValidateModuleId(moduleId); err != nil {
+		panic(err)
+	}
+	// 没有指定节点 ID 时在启动时输出警告
+	defaultGen()
+
+	return func(c *gin.Context) {
+		m := MetadataFromRequest(c.Request)
+		if m.Sid == "" {
+			m.Sid = NewSid(moduleId)
+			c.Request.Header.Set(HeaderSid, m.Sid)
+		}
+
+		c.Request = c.Request.WithContext(NewContext(c.Request.Context(), m))
+		c.Next()
+	}
+}
+
+// Deadline 返回 gin 中间件，按调用方传来的 HeaderTimeout 设置请求 context 的超时时间，
+// 之后使用该 context 的调用会在调用方放弃前结束
+func Deadline() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ms, err := strconv.ParseInt(c.GetHeader(HeaderTimeout), 10, 64)
+		if err != nil || ms <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(ms)*time.Millisecond)
+		defer cancel()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
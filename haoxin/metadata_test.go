@@ -0,0 +1,62 @@
+package haoxin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware("40"))
+
+	var got Metadata
+	router.GET("/", func(c *gin.Context) {
+		got, _ = FromContext(c)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(HeaderTid, "t1")
+	req.Header.Set(HeaderUserId, "u1")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	if len(got.Sid) != 22 || got.Sid[:2] != "40" || got.Tid != "t1" || got.UserId != "u1" {
+		t.Errorf("metadata got = %+v", got)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(HeaderSid, "s1")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	if got.Sid != "s1" {
+		t.Errorf("sid got = %s", got.Sid)
+	}
+}
+
+func TestDeadline(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Deadline())
+
+	var remaining time.Duration
+	var ok bool
+	router.GET("/", func(c *gin.Context) {
+		var deadline time.Time
+		deadline, ok = c.Request.Context().Deadline()
+		remaining = time.Until(deadline)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(HeaderTimeout, "1500")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	if !ok || remaining <= time.Second || remaining > 1500*time.Millisecond {
+		t.Errorf("deadline got = %v, %v", remaining, ok)
+	}
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if ok {
+		t.Errorf("request without timeout header should not have deadline")
+	}
+}
@@ -0,0 +1,334 @@
+package rpc
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/chengjianxi/goc/haoxin/log"
+)
+
+// BreakerState 熔断器状态
+type BreakerState int
+
+const (
+	StateClosed   BreakerState = iota // 正常放行，统计失败率
+	StateOpen                         // 拒绝所有调用，OpenTimeout 后进入半开
+	StateHalfOpen                     // 放行少量探测调用，全部成功后关闭，任意失败重新打开
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// BreakerConfig 熔断器配置
+type BreakerConfig struct {
+	Window           time.Duration // 统计失败率的滑动窗口，默认 10 秒
+	Buckets          int           // 窗口分桶数，默认 10
+	MinRequests      int           // 窗口内调用次数达到此值才计算失败率，默认 20
+	FailureRatio     float64       // 失败率达到此值时打开，默认 0.5
+	OpenTimeout      time.Duration // 打开后经过此时间进入半开，默认 5 秒
+	HalfOpenRequests int           // 半开时放行的探测调用数，默认 1
+	IdleTimeout      time.Duration // Breakers 中超过此时间没有使用的熔断器会被清理，默认 10 分钟
+
+	// IsFailure 判断调用是否失败，默认网络错误、超时和 5xx 为失败
+	IsFailure func(resp *Response, err error) bool
+	// Fallback 熔断器拒绝调用时返回的结果，默认返回 ErrCircuitOpen，也可以通过 OptionWithFallback 单次设置
+	Fallback func(ctx context.Context, key string, err error) (*Response, error)
+	// OnStateChange 状态变更时调用，状态变更同时记录到 Logger
+	OnStateChange func(key string, from BreakerState, to BreakerState)
+	// Logger 记录状态变更，默认 log.Default()
+	Logger *log.Logger
+}
+
+func (cfg BreakerConfig) withDefaults() BreakerConfig {
+	if cfg.Window <= 0 {
+		cfg.Window = 10 * time.Second
+	}
+	if cfg.Buckets <= 0 {
+		cfg.Buckets = 10
+	}
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = 20
+	}
+	if cfg.FailureRatio <= 0 || cfg.FailureRatio > 1 {
+		cfg.FailureRatio = 0.5
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = 5 * time.Second
+	}
+	if cfg.HalfOpenRequests <= 0 {
+		cfg.HalfOpenRequests = 1
+	}
+	if cfg.IdleTimeout <= 0 {
+		cfg.IdleTimeout = 10 * time.Minute
+	}
+	if cfg.IsFailure == nil {
+		cfg.IsFailure = isFailure
+	}
+	return cfg
+}
+
+func isFailure(resp *Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode >= http.StatusInternalServerError
+}
+
+type bucket struct {
+	id       int64 // 所属时间段，过期后重置
+	total    int
+	failures int
+}
+
+// CircuitBreaker 单个下游服务的熔断器
+type CircuitBreaker struct {
+	key string
+	cfg BreakerConfig
+
+	mux        sync.Mutex
+	state      BreakerState
+	generation uint64 // 每次状态变更加一，忽略变更前放行的调用结果
+	buckets    []bucket
+	openedAt   time.Time
+	inflight   int // 半开时已放行的调用数
+	successes  int // 半开时成功的调用数
+	now        func() time.Time
+}
+
+// NewCircuitBreaker 创建熔断器，key 为服务名或地址，用于日志
+func NewCircuitBreaker(key string, cfg BreakerConfig) *CircuitBreaker {
+	cfg = cfg.withDefaults()
+	return &CircuitBreaker{
+		key:     key,
+		cfg:     cfg,
+		buckets: make([]bucket, cfg.Buckets),
+		now:     time.Now,
+	}
+}
+
+// State 返回当前状态，打开超过 OpenTimeout 时返回 StateHalfOpen
+func (b *CircuitBreaker) State() BreakerState {
+	b.mux.Lock()
+	state, from, to := b.currentState()
+	b.mux.Unlock()
+
+	b.notify(from, to)
+	return state
+}
+
+// Do 熔断器放行时执行 fn 并记录结果，否则返回 ErrCircuitOpen。
+// ctx 被取消导致的错误不计入失败率
+func (b *CircuitBreaker) Do(ctx context.Context, fn func() (*Response, error)) (*Response, error) {
+	generation, ok := b.allow()
+	if !ok {
+		return nil, ErrCircuitOpen
+	}
+
+	resp, err := fn()
+	if err != nil && ctx.Err() != nil {
+		b.release(generation)
+	} else {
+		b.record(generation, b.cfg.IsFailure(resp, err))
+	}
+	return resp, err
+}
+
+func (b *CircuitBreaker) allow() (uint64, bool) {
+	b.mux.Lock()
+	state, from, to := b.currentState()
+	ok := true
+	switch state {
+	case StateOpen:
+		ok = false
+	case StateHalfOpen:
+		if b.inflight >= b.cfg.HalfOpenRequests {
+			ok = false
+		} else {
+			b.inflight++
+		}
+	}
+	generation := b.generation
+	b.mux.Unlock()
+
+	b.notify(from, to)
+	return generation, ok
+}
+
+func (b *CircuitBreaker) release(generation uint64) {
+	b.mux.Lock()
+	if generation == b.generation && b.state == StateHalfOpen {
+		b.inflight--
+	}
+	b.mux.Unlock()
+}
+
+func (b *CircuitBreaker) record(generation uint64, failure bool) {
+	b.mux.Lock()
+	from, to := b.state, b.state
+	if generation == b.generation {
+		switch b.state {
+		case StateClosed:
+			total, failures := b.count(failure)
+			if total >= b.cfg.MinRequests && float64(failures)/float64(total) >= b.cfg.FailureRatio {
+				to = StateOpen
+			}
+		case StateHalfOpen:
+			if failure {
+				to = StateOpen
+			} else if b.successes++; b.successes >= b.cfg.HalfOpenRequests {
+				to = StateClosed
+			}
+		}
+		b.setState(to)
+	}
+	b.mux.Unlock()
+
+	b.notify(from, to)
+}
+
+// currentState 打开超过 OpenTimeout 时进入半开，需要持有锁
+func (b *CircuitBreaker) currentState() (state BreakerState, from BreakerState, to BreakerState) {
+	from = b.state
+	if b.state == StateOpen && b.now().Sub(b.openedAt) >= b.cfg.OpenTimeout {
+		b.setState(StateHalfOpen)
+	}
+	return b.state, from, b.state
+}
+
+func (b *CircuitBreaker) setState(state BreakerState) {
+	if state == b.state {
+		return
+	}
+
+	b.state = state
+	b.generation++
+	b.inflight = 0
+	b.successes = 0
+	switch state {
+	case StateOpen:
+		b.openedAt = b.now()
+	case StateClosed:
+		for i := range b.buckets {
+			b.buckets[i] = bucket{}
+		}
+	}
+}
+
+// count 记录一次调用结果并返回窗口内的调用数和失败数，需要持有锁
+func (b *CircuitBreaker) count(failure bool) (total int, failures int) {
+	size := int64(b.cfg.Window) / int64(len(b.buckets))
+	if size <= 0 {
+		size = 1
+	}
+	id := b.now().UnixNano() / size
+
+	cur := &b.buckets[id%int64(len(b.buckets))]
+	if cur.id != id {
+		*cur = bucket{id: id}
+	}
+	cur.total++
+	if failure {
+		cur.failures++
+	}
+
+	for _, bk := range b.buckets {
+		if bk.id > id-int64(len(b.buckets)) {
+			total += bk.total
+			failures += bk.failures
+		}
+	}
+	return total, failures
+}
+
+// notify 在锁外调用 OnStateChange 并记录日志
+func (b *CircuitBreaker) notify(from BreakerState, to BreakerState) {
+	if from == to {
+		return
+	}
+
+	logger := b.cfg.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
+	logger = logger.With(map[string]interface{}{"breaker": b.key, "from": from.String(), "to": to.String()})
+	if to == StateOpen {
+		logger.Warnf("熔断器 %s 打开，%s 后尝试恢复", b.key, b.cfg.OpenTimeout)
+	} else {
+		logger.Infof("熔断器 %s 状态变更：%s -> %s", b.key, from, to)
+	}
+
+	if b.cfg.OnStateChange != nil {
+		b.cfg.OnStateChange(b.key, from, to)
+	}
+}
+
+// Breakers 按服务名或地址分别创建熔断器，没有服务名时按地址区分，
+// 服务发现的地址变化后不再使用的熔断器在 IdleTimeout 后清理
+//
+// use it:
+//
+//	client := rpc.NewClient(rpc.ClientConfig{Breakers: rpc.NewBreakers(rpc.BreakerConfig{})})
+//	resp, err := client.Do(ctx, http.MethodGet, addr, "/users/1", nil, nil, rpc.OptionWithService("USER-SVC"))
+//	if errors.Is(err, rpc.ErrCircuitOpen) {
+//		...
+//	}
+type Breakers struct {
+	cfg BreakerConfig
+
+	mux       sync.Mutex
+	breakers  map[string]*breakerEntry
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type breakerEntry struct {
+	breaker  *CircuitBreaker
+	lastUsed time.Time
+}
+
+func NewBreakers(cfg BreakerConfig) *Breakers {
+	cfg = cfg.withDefaults()
+	return &Breakers{cfg: cfg, breakers: make(map[string]*breakerEntry), lastSweep: time.Now(), now: time.Now}
+}
+
+// Get 返回 key 对应的熔断器，不存在时创建
+func (g *Breakers) Get(key string) *CircuitBreaker {
+	g.mux.Lock()
+	defer g.mux.Unlock()
+
+	now := g.now()
+	g.sweep(now)
+
+	e, ok := g.breakers[key]
+	if !ok {
+		e = &breakerEntry{breaker: NewCircuitBreaker(key, g.cfg)}
+		g.breakers[key] = e
+	}
+	e.lastUsed = now
+	return e.breaker
+}
+
+// sweep 每隔 IdleTimeout 清理一次超过 IdleTimeout 没有使用的熔断器，需要持有锁
+func (g *Breakers) sweep(now time.Time) {
+	if now.Sub(g.lastSweep) < g.cfg.IdleTimeout {
+		return
+	}
+	g.lastSweep = now
+
+	for key, e := range g.breakers {
+		if now.Sub(e.lastUsed) >= g.cfg.IdleTimeout {
+			delete(g.breakers, key)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chengjianxi/goc/haoxin/log"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	var out bytes.Buffer
+	var changes []string
+	b := NewCircuitBreaker("USER-SVC", BreakerConfig{
+		Window:       time.Second,
+		MinRequests:  4,
+		FailureRatio: 0.5,
+		OpenTimeout:  time.Second,
+		OnStateChange: func(key string, from BreakerState, to BreakerState) {
+			changes = append(changes, from.String()+"->"+to.String())
+		},
+		Logger: log.New(log.WithOutputs(&out)),
+	})
+	now := time.Unix(1700000000, 0)
+	b.now = func() time.Time { return now }
+
+	ok := func() (*Response, error) { return &Response{StatusCode: http.StatusOK}, nil }
+	fail := func() (*Response, error) { return &Response{StatusCode: http.StatusBadGateway}, nil }
+	ctx := context.Background()
+
+	// 调用次数不足时不计算失败率
+	for _, fn := range []func() (*Response, error){ok, fail, fail} {
+		b.Do(ctx, fn)
+	}
+	if b.State() != StateClosed {
+		t.Fatalf("state got = %s", b.State())
+	}
+
+	// 窗口外的调用不计入
+	now = now.Add(2 * time.Second)
+	b.Do(ctx, fail)
+	if b.State() != StateClosed {
+		t.Fatalf("state got = %s", b.State())
+	}
+
+	for _, fn := range []func() (*Response, error){ok, fail, ok} {
+		b.Do(ctx, fn)
+	}
+	if b.State() != StateOpen {
+		t.Fatalf("state got = %s", b.State())
+	}
+	if _, err := b.Do(ctx, ok); err != ErrCircuitOpen {
+		t.Errorf("Do() err = %v", err)
+	}
+
+	// 半开时只放行一个探测调用，失败后重新打开
+	now = now.Add(time.Second)
+	if b.State() != StateHalfOpen {
+		t.Fatalf("state got = %s", b.State())
+	}
+	b.Do(ctx, func() (*Response, error) {
+		if _, err := b.Do(ctx, ok); err != ErrCircuitOpen {
+			t.Errorf("Do() err = %v", err)
+		}
+		return fail()
+	})
+	if b.State() != StateOpen {
+		t.Fatalf("state got = %s", b.State())
+	}
+
+	// 探测成功后关闭
+	now = now.Add(time.Second)
+	if _, err := b.Do(ctx, ok); err != nil || b.State() != StateClosed {
+		t.Fatalf("Do() err = %v, state = %s", err, b.State())
+	}
+
+	expected := []string{"closed->open", "open->half-open", "half-open->open", "open->half-open", "half-open->closed"}
+	if strings.Join(changes, ",") != strings.Join(expected, ",") {
+		t.Errorf("changes got = %v", changes)
+	}
+	if !strings.Contains(out.String(), "熔断器 USER-SVC 打开") || !strings.Contains(out.String(), `"to":"closed"`) {
+		t.Errorf("log got = %s", out.String())
+	}
+}
+
+func TestCircuitBreakerIgnoreCanceled(t *testing.T) {
+	b := NewCircuitBreaker("USER-SVC", BreakerConfig{MinRequests: 1, Logger: log.New(log.WithOutputs(&bytes.Buffer{}))})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	b.Do(ctx, func() (*Response, error) { return nil, context.Canceled })
+	if b.State() != StateClosed {
+		t.Errorf("state got = %s", b.State())
+	}
+}
+
+func TestClientBreaker(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	breakers := NewBreakers(BreakerConfig{MinRequests: 2, Logger: log.New(log.WithOutputs(&bytes.Buffer{}))})
+	client := NewClient(ClientConfig{Breakers: breakers})
+	for i := 0; i < 2; i++ {
+		client.Do(context.Background(), http.MethodGet, server.URL, "/users/1", nil, nil, OptionWithService("USER-SVC"))
+	}
+	if breakers.Get("USER-SVC").State() != StateOpen {
+		t.Fatalf("state got = %s", breakers.Get("USER-SVC").State())
+	}
+
+	_, err := client.Do(context.Background(), http.MethodGet, server.URL, "/users/1", nil, nil, OptionWithService("USER-SVC"))
+	var rpcErr *Error
+	if !errors.Is(err, ErrCircuitOpen) || !errors.As(err, &rpcErr) || !strings.Contains(err.Error(), "USER-SVC") {
+		t.Errorf("Do() err = %v", err)
+	}
+
+	// 其他服务不受影响
+	if _, err := client.Do(context.Background(), http.MethodGet, server.URL, "/orders/1", nil, nil, OptionWithService("ORDER-SVC")); err != nil {
+		t.Errorf("Do() err = %v", err)
+	}
+
+	fallback := func(ctx context.Context, key string, err error) (*Response, error) {
+		return &Response{StatusCode: http.StatusOK, Body: []byte("cached " + key)}, nil
+	}
+	resp, err := client.Do(context.Background(), http.MethodGet, server.URL, "/users/1", nil, nil, OptionWithService("USER-SVC"), OptionWithFallback(fallback))
+	if err != nil || resp.String() != "cached USER-SVC" {
+		t.Errorf("Do() got = %+v, err = %v", resp, err)
+	}
+}
+
+func TestClientBreakerRetry(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+	}))
+	defer server.Close()
+
+	const down = "http://127.0.0.1:1"
+	breakers := NewBreakers(BreakerConfig{Logger: log.New(log.WithOutputs(&bytes.Buffer{}))})
+	for _, key := range []string{down, "USER-SVC"} {
+		b := breakers.Get(key)
+		b.mux.Lock()
+		b.setState(StateOpen)
+		b.mux.Unlock()
+	}
+	client := NewClient(ClientConfig{Breakers: breakers, Retry: RetryPolicy{MaxAttempts: 3, InitialBackoff: 50 * time.Millisecond}})
+
+	tests := []struct {
+		name    string
+		opts    []Option
+		wantErr bool
+		hits    int
+	}{
+		{"next addr", []Option{OptionWithNextAddr(func() string { return server.URL })}, false, 1},
+		{"same addr", []Option{OptionWithNextAddr(func() string { return down })}, true, 0},
+		{"no next addr", nil, true, 0},
+		{"same service", []Option{OptionWithService("USER-SVC"), OptionWithNextAddr(func() string { return server.URL })}, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits = 0
+			start := time.Now()
+			_, err := client.Do(context.Background(), http.MethodGet, down, "/users/1", nil, nil, tt.opts...)
+			if (err != nil) != tt.wantErr || tt.wantErr && !errors.Is(err, ErrCircuitOpen) || hits != tt.hits {
+				t.Errorf("Do() err = %v, hits = %d", err, hits)
+			}
+			// 不重试时没有等待
+			if elapsed := time.Since(start); tt.wantErr && elapsed >= 30*time.Millisecond {
+				t.Errorf("Do() retried, elapsed = %s", elapsed)
+			}
+		})
+	}
+}
+
+func TestBreakersIdle(t *testing.T) {
+	breakers := NewBreakers(BreakerConfig{IdleTimeout: time.Minute})
+	now := time.Unix(1700000000, 0)
+	breakers.now = func() time.Time { return now }
+	breakers.lastSweep = now
+
+	old := breakers.Get("http://10.0.0.1:8080")
+	used := breakers.Get("http://10.0.0.2:8080")
+	now = now.Add(40 * time.Second)
+	breakers.Get("http://10.0.0.2:8080")
+
+	// 超过 IdleTimeout 没有使用的熔断器被清理，使用中的保留
+	now = now.Add(30 * time.Second)
+	if b := breakers.Get("http://10.0.0.2:8080"); b != used {
+		t.Errorf("breaker in use should be kept")
+	}
+	if len(breakers.breakers) != 1 {
+		t.Errorf("breakers got = %d", len(breakers.breakers))
+	}
+	if b := breakers.Get("http://10.0.0.1:8080"); b == old {
+		t.Errorf("idle breaker should be removed")
+	}
+}
@@ -0,0 +1,138 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrBusiness 服务返回的 code 不表示成功，通过 errors.As 获取 *BusinessError
+var ErrBusiness = errors.New("服务返回业务错误")
+
+// BusinessError 服务按 {code,msg,data} 格式返回了错误
+type BusinessError struct {
+	Code     int
+	Msg      string
+	Response *Response
+}
+
+func (e *BusinessError) Error() string {
+	return fmt.Sprintf("%s，code %d，%s", ErrBusiness, e.Code, e.Msg)
+}
+
+func (e *BusinessError) Is(target error) bool {
+	return target == ErrBusiness
+}
+
+// 服务之间统一的响应格式
+type envelope struct {
+	Code *int            `json:"code"`
+	Msg  string          `json:"msg"`
+	Data json.RawMessage `json:"data"`
+}
+
+// parseEnvelope 响应为 JSON 对象、code 为数字并且有 msg 或 data 时才认为是 {code,msg,data} 格式，
+// 避免把自身带 code 字段的响应当成统一格式
+func parseEnvelope(body []byte) (envelope, bool) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return envelope{}, false
+	}
+	_, hasMsg := fields["msg"]
+	_, hasData := fields["data"]
+	if _, ok := fields["code"]; !ok || !hasMsg && !hasData {
+		return envelope{}, false
+	}
+
+	var env envelope
+	if err := json.Unmarshal(body, &env); err != nil || env.Code == nil {
+		return envelope{}, false
+	}
+	return env, true
+}
+
+// Call 调用服务并解析 JSON 响应，client 为 nil 时使用 DefaultClient。
+// GET、HEAD、DELETE 请求 req 作为 query 参数，其他请求 req 编码为 JSON body。
+//
+// 响应为 {code,msg,data} 格式（有数字 code，并且有 msg 或 data）时 data 解析到 Resp，code 不是成功时返回 *BusinessError；
+// 不是该格式或设置了 ClientConfig.RawResponse、OptionWithRawResponse 时整个响应解析到 Resp，非 2xx 响应返回 ErrClient 或 ErrServer，
+// 2xx 响应没有 body 时返回 Resp 的零值。
+//
+// use it:
+//
+//	order, err := rpc.Call[GetOrderReq, Order](ctx, nil, http.MethodGet, addr, "/orders", GetOrderReq{Id: 42})
+//	var bizErr *rpc.BusinessError
+//	if errors.As(err, &bizErr) && bizErr.Code == 404 {
+//		...
+//	}
+func Call[Req any, Resp any](ctx context.Context, client *Client, method string, addr string, uri string, req Req, opts ...Option) (Resp, error) {
+	var result Resp
+	if client == nil {
+		client = DefaultClient
+	}
+
+	var query, body interface{}
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodDelete:
+		query = req
+	default:
+		body = req
+	}
+
+	resp, err := client.Do(ctx, method, addr, uri, query, body, opts...)
+	if err != nil {
+		return result, err
+	}
+	url := joinURL(addr, uri)
+
+	env, ok := parseEnvelope(resp.Body)
+	if !ok || client.rawResponse(opts) {
+		if !resp.OK() {
+			return result, newStatusError(method, url, resp)
+		}
+		// 204、HEAD 等没有 body 的响应返回零值
+		if len(resp.Body) == 0 {
+			return result, nil
+		}
+		if err := json.Unmarshal(resp.Body, &result); err != nil {
+			return result, &Error{Kind: ErrDecode, Method: method, URL: url, Response: resp, Err: err}
+		}
+		return result, nil
+	}
+
+	if *env.Code != client.successCode(opts) {
+		return result, &BusinessError{Code: *env.Code, Msg: env.Msg, Response: resp}
+	}
+	if !resp.OK() {
+		return result, newStatusError(method, url, resp)
+	}
+
+	if len(env.Data) != 0 && string(env.Data) != "null" {
+		if err := json.Unmarshal(env.Data, &result); err != nil {
+			return result, &Error{Kind: ErrDecode, Method: method, URL: url, Response: resp, Err: err}
+		}
+	}
+	return result, nil
+}
+
+func (c *Client) successCode(opts []Option) int {
+	code := c.cfg.SuccessCode
+	for _, opt := range opts {
+		if opt.successCode != nil {
+			code = *opt.successCode
+		}
+	}
+	return code
+}
+
+func (c *Client) rawResponse(opts []Option) bool {
+	raw := c.cfg.RawResponse
+	for _, opt := range opts {
+		if opt.rawResponse {
+			raw = true
+		}
+	}
+	return raw
+}
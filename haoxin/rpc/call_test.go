@@ -0,0 +1,115 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+type orderReq struct {
+	Id int `json:"id"`
+}
+
+type order struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type orderStatus struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+	Data string `json:"data"`
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestCall(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/orders":
+			if r.URL.Query().Get("id") != "42" {
+				t.Errorf("query got = %s", r.URL.RawQuery)
+			}
+			w.Write([]byte(`{"code":0,"msg":"ok","data":{"id":42,"name":"book"}}`))
+		case "/create":
+			var req orderReq
+			json.NewDecoder(r.Body).Decode(&req)
+			w.Write([]byte(`{"code":200,"msg":"ok","data":{"id":` + strconv.Itoa(req.Id) + `}}`))
+		case "/denied":
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"code":1001,"msg":"没有权限"}`))
+		case "/raw":
+			w.Write([]byte(`{"id":7,"name":"pen"}`))
+		case "/broken":
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte("<html>bad gateway</html>"))
+		case "/invalid":
+			w.Write([]byte(`{"code":0,"data":"not an order"}`))
+		case "/empty":
+			w.WriteHeader(http.StatusNoContent)
+		case "/status":
+			w.Write([]byte(`{"code":3,"id":9,"name":"cup"}`))
+		case "/ambiguous":
+			w.Write([]byte(`{"code":0,"msg":"paid","data":"extra"}`))
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	o, err := Call[orderReq, order](ctx, nil, http.MethodGet, server.URL, "/orders", orderReq{Id: 42})
+	if err != nil || o != (order{Id: 42, Name: "book"}) {
+		t.Errorf("Call() got = %+v, err = %v", o, err)
+	}
+
+	o, err = Call[orderReq, order](ctx, nil, http.MethodPost, server.URL, "/create", orderReq{Id: 5}, OptionWithSuccessCode(200))
+	if err != nil || o.Id != 5 {
+		t.Errorf("Call() got = %+v, err = %v", o, err)
+	}
+
+	_, err = Call[orderReq, order](ctx, nil, http.MethodGet, server.URL, "/denied", orderReq{})
+	var bizErr *BusinessError
+	if !errors.Is(err, ErrBusiness) || !errors.As(err, &bizErr) || bizErr.Code != 1001 || bizErr.Msg != "没有权限" ||
+		bizErr.Response.StatusCode != http.StatusForbidden {
+		t.Errorf("Call() err = %v", err)
+	}
+
+	o, err = Call[orderReq, order](ctx, nil, http.MethodGet, server.URL, "/raw", orderReq{})
+	if err != nil || o.Name != "pen" {
+		t.Errorf("Call() got = %+v, err = %v", o, err)
+	}
+
+	if _, err := Call[orderReq, order](ctx, nil, http.MethodGet, server.URL, "/broken", orderReq{}); !errors.Is(err, ErrServer) {
+		t.Errorf("Call() err = %v", err)
+	}
+	if _, err := Call[orderReq, order](ctx, nil, http.MethodGet, server.URL, "/invalid", orderReq{}); !errors.Is(err, ErrDecode) {
+		t.Errorf("Call() err = %v", err)
+	}
+
+	// 没有 body 的 2xx 响应返回零值
+	for _, method := range []string{http.MethodDelete, http.MethodHead} {
+		o, err = Call[orderReq, order](ctx, nil, method, server.URL, "/empty", orderReq{})
+		if err != nil || o != (order{}) {
+			t.Errorf("Call(%s) got = %+v, err = %v", method, o, err)
+		}
+	}
+
+	// 自身带 code 字段的响应不是 {code,msg,data} 格式
+	s, err := Call[orderReq, orderStatus](ctx, nil, http.MethodGet, server.URL, "/status", orderReq{})
+	if err != nil || s != (orderStatus{Code: 3, Id: 9, Name: "cup"}) {
+		t.Errorf("Call() got = %+v, err = %v", s, err)
+	}
+
+	s, err = Call[orderReq, orderStatus](ctx, nil, http.MethodGet, server.URL, "/ambiguous", orderReq{}, OptionWithRawResponse())
+	if err != nil || s != (orderStatus{Msg: "paid", Data: "extra"}) {
+		t.Errorf("Call() got = %+v, err = %v", s, err)
+	}
+	client := NewClient(ClientConfig{RawResponse: true})
+	s, err = Call[orderReq, orderStatus](ctx, client, http.MethodGet, server.URL, "/ambiguous", orderReq{})
+	if err != nil || s != (orderStatus{Msg: "paid", Data: "extra"}) {
+		t.Errorf("Call() got = %+v, err = %v", s, err)
+	}
+}
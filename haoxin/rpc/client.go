@@ -0,0 +1,356 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chengjianxi/goc/haoxin"
+	"github.com/chengjianxi/goc/haoxin/balancer"
+	"github.com/chengjianxi/goc/haoxin/tracing"
+	"github.com/chengjianxi/goc/secure"
+)
+
+// DefaultTimeout 未设置超时时间时的默认值
+const DefaultTimeout = 10 * time.Second
+
+// ClientConfig 调用服务的配置
+type ClientConfig struct {
+	Timeout     time.Duration  // 每次调用的默认超时时间，默认 DefaultTimeout
+	Signer      *secure.Signer // 请求签名，默认使用 SetSigner 设置的
+	CheckStatus bool           // 为 true 时非 2xx 响应返回 ErrClient 或 ErrServer，也可以通过 OptionWithCheckStatus 单次设置
+	SuccessCode int            // Call 解析响应时表示成功的 code，默认 0，也可以通过 OptionWithSuccessCode 单次设置
+	RawResponse bool           // 为 true 时 Call 不按 {code,msg,data} 解析，整个响应解析到 Resp，也可以通过 OptionWithRawResponse 单次设置
+	Retry       RetryPolicy    // 重试策略，默认不重试，svc:// 地址默认换一个实例重试一次，也可以通过 OptionWithRetry 单次设置
+	Breakers    *Breakers      // 按服务熔断，默认使用 SetBreakers 设置的，服务名通过 OptionWithService 设置
+
+	Resolver Resolver          // 解析 svc:// 地址中的服务名，默认使用 SetResolver 设置的
+	Balancer balancer.Balancer // 从服务实例中选择地址，默认 balancer.NewRoundRobin
+
+	Transport http.RoundTripper // 连接池，默认所有 Client 共享，见 NewTransport
+}
+
+// Client 调用其他服务，每次调用的超时时间取 ctx 的 deadline 和超时设置中较早的一个，
+// 剩余时间通过 haoxin.HeaderTimeout 传给被调用方，见 haoxin.Deadline
+//
+// use it:
+//
+//	client := rpc.NewClient(rpc.ClientConfig{Timeout: 3 * time.Second})
+//	resp, err := client.Do(ctx, http.MethodGet, addr, "/orders", query, nil, rpc.OptionWithTimeout(time.Second))
+//	if errors.Is(err, rpc.ErrTimeout) {
+//		...
+//	}
+//
+// 通过服务名调用：
+//
+//	client := rpc.NewClient(rpc.ClientConfig{Resolver: e})
+//	resp, err := client.Do(ctx, http.MethodGet, "", "svc://USER-SVC/users/1", nil, nil)
+type Client struct {
+	cfg        ClientConfig
+	httpClient *http.Client
+}
+
+// DefaultClient Rpc 和 RpcContext 使用的 Client
+var DefaultClient = NewClient(ClientConfig{})
+
+func NewClient(cfg ClientConfig) *Client {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.Balancer == nil {
+		cfg.Balancer = balancer.NewRoundRobin(0)
+	}
+	if cfg.Transport == nil {
+		cfg.Transport = defaultTransport
+	}
+	// 超时由每次调用的 ctx 控制
+	return &Client{cfg: cfg, httpClient: &http.Client{Transport: cfg.Transport}}
+}
+
+// Do 调用服务，sid、tid、userid、machineid 从 ctx 中获取，见 haoxin.NewContext。
+// addr 为 svc://NAME 或 addr 为空、uri 为 svc://NAME/path 时通过 Resolver 解析服务地址并负载均衡。
+// 返回的错误为 *Error，可以通过 errors.Is 判断 ErrTransport、ErrTimeout、ErrClient、ErrServer
+func (c *Client) Do(ctx context.Context, method string, addr string, uri string, query interface{}, body interface{}, opts ...Option) (*Response, error) {
+	m, _ := haoxin.FromContext(ctx)
+	return c.call(ctx, m, method, addr, uri, query, body, opts...)
+}
+
+// 同时发送 sid/tid 和 traceparent/tracestate Header，每次调用都创建 span，见 tracing.Propagator
+func (c *Client) call(ctx context.Context, m haoxin.Metadata, method string, addr string, uri string, query interface{}, body interface{}, opts ...Option) (*Response, error) {
+	signer := c.cfg.Signer
+	if signer == nil {
+		signer = defaultSigner
+	}
+	timeout := c.cfg.Timeout
+	checkStatus := c.cfg.CheckStatus
+	retry := c.cfg.Retry
+	idempotencyKey := ""
+	service := ""
+	var next func() string
+	breakers := c.cfg.Breakers
+	if breakers == nil {
+		breakers = defaultBreakers
+	}
+	var fallback func(ctx context.Context, key string, err error) (*Response, error)
+	if breakers != nil {
+		fallback = breakers.cfg.Fallback
+	}
+
+	for _, opt := range opts {
+		if opt.Sid != "" {
+			m.Sid = opt.Sid
+		}
+
+		if opt.Tid != "" {
+			m.Tid = opt.Tid
+		}
+
+		if opt.Signer != nil {
+			signer = opt.Signer
+		}
+
+		if opt.Timeout > 0 {
+			timeout = opt.Timeout
+		}
+
+		if opt.CheckStatus {
+			checkStatus = true
+		}
+
+		if opt.Retry != nil {
+			retry = *opt.Retry
+		}
+
+		if opt.IdempotencyKey != "" {
+			idempotencyKey = opt.IdempotencyKey
+		}
+
+		if opt.NextAddr != nil {
+			next = opt.NextAddr
+		}
+
+		if opt.Service != "" {
+			service = opt.Service
+		}
+
+		if opt.Fallback != nil {
+			fallback = opt.Fallback
+		}
+	}
+
+	// svc:// 地址每次调用前解析并由 Balancer 选择实例
+	name := ""
+	if n, p, ok := parseService(addr); ok {
+		name, addr, uri = n, "", p+uri
+	} else if n, p, ok := parseService(uri); ok && addr == "" {
+		name, uri = n, p
+	}
+	if name != "" {
+		resolver := c.cfg.Resolver
+		if resolver == nil {
+			resolver = defaultResolver
+		}
+		if resolver == nil {
+			return nil, fmt.Errorf("%w：没有设置 Resolver，无法解析 %s%s", ErrServiceNotFound, SchemeService, name)
+		}
+
+		if service == "" {
+			service = name
+		}
+		if retry.MaxAttempts == 0 {
+			retry.MaxAttempts = 2
+		}
+
+		var resolveErr error
+		next = func() string {
+			addrs, err := resolver.Resolve(ctx, name)
+			if err != nil {
+				resolveErr = err
+				return ""
+			}
+			return c.cfg.Balancer.Pick(name, addrs)
+		}
+		if addr = next(); addr == "" {
+			if resolveErr != nil {
+				return nil, resolveErr
+			}
+			return nil, fmt.Errorf("%w：%s", ErrServiceNotFound, name)
+		}
+	}
+
+	if addr == "" && next != nil {
+		addr = next()
+	}
+	if addr == "" {
+		return nil, ErrServiceNotFound
+	}
+
+	maxAttempts := 1
+	if retry.MaxAttempts > 1 && (idempotent(method) || idempotencyKey != "") {
+		maxAttempts = retry.MaxAttempts
+		retry = retry.withDefaults()
+	}
+
+	values, err := encodeQuery(query)
+	if err != nil {
+		return nil, &Error{Kind: ErrTransport, Method: method, URL: joinURL(addr, uri), Err: err}
+	}
+	content, contentType, err := encodeBody(body)
+	if err != nil {
+		return nil, &Error{Kind: ErrTransport, Method: method, URL: joinURL(addr, uri), Err: err}
+	}
+	rawQuery := values.Encode()
+
+	var failed map[string]bool
+	for attempt := 1; ; attempt++ {
+		url := joinURL(addr, uri)
+		key := service
+		if key == "" {
+			key = addr
+		}
+
+		resp, err := breakerAttempt(ctx, breakers, key, func() (*Response, error) {
+			return c.attempt(ctx, m, method, url, rawQuery, content, contentType, signer, timeout, idempotencyKey)
+		})
+		if err == ErrCircuitOpen {
+			err = &Error{Kind: ErrCircuitOpen, Method: method, URL: url, Err: fmt.Errorf("熔断器 %s", key)}
+		} else if name != "" && (err == nil || ctx.Err() == nil) {
+			c.cfg.Balancer.Feedback(name, addr, !isFailure(resp, err))
+		}
+
+		retryable := attempt < maxAttempts && retry.retryable(ctx, resp, err)
+
+		// 有服务发现时换一个实例重试
+		nextAddr := addr
+		if retryable && next != nil {
+			if failed == nil {
+				failed = make(map[string]bool)
+			}
+			failed[addr] = true
+			if a := pickAddr(next, failed); a != "" {
+				nextAddr = a
+			}
+		}
+		// 熔断器按服务名或地址区分，下一次调用使用同一个熔断器时重试也会被拒绝
+		if errors.Is(err, ErrCircuitOpen) && (service != "" || nextAddr == addr) {
+			retryable = false
+		}
+
+		if !retryable || !sleep(ctx, retry.backoff(attempt)) {
+			if errors.Is(err, ErrCircuitOpen) && fallback != nil {
+				return fallback(ctx, key, err)
+			}
+			if err == nil && checkStatus && !resp.OK() {
+				err = newStatusError(method, url, resp)
+			}
+			return resp, err
+		}
+		addr = nextAddr
+	}
+}
+
+// breakerAttempt breakers 不为 nil 时通过 key 对应的熔断器调用 fn
+func breakerAttempt(ctx context.Context, breakers *Breakers, key string, fn func() (*Response, error)) (*Response, error) {
+	if breakers == nil {
+		return fn()
+	}
+	return breakers.Get(key).Do(ctx, fn)
+}
+
+// attempt 发送一次请求，超时时间对每次调用单独计算
+func (c *Client) attempt(ctx context.Context, m haoxin.Metadata, method string, url string, rawQuery string, content []byte, contentType string, signer *secure.Signer, timeout time.Duration, idempotencyKey string) (*Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ctx, span := tracing.StartClientSpan(haoxin.NewContext(ctx, m), method, url)
+	resp, err := c.do(ctx, method, withQuery(url, rawQuery), content, contentType, signer, idempotencyKey)
+
+	statusCode := 0
+	if resp != nil {
+		statusCode = resp.StatusCode
+	}
+	tracing.EndSpan(span, statusCode, err)
+
+	if err != nil {
+		kind := ErrTransport
+		if errors.Is(err, context.DeadlineExceeded) {
+			kind = ErrTimeout
+		}
+		return resp, &Error{Kind: kind, Method: method, URL: url, Response: resp, Err: err}
+	}
+
+	return resp, nil
+}
+
+// joinURL 拼接服务地址和路径，两者之间只保留一个 "/"
+func joinURL(addr string, uri string) string {
+	if addr == "" || uri == "" || strings.HasPrefix(uri, "?") {
+		return addr + uri
+	}
+	return strings.TrimSuffix(addr, "/") + "/" + strings.TrimPrefix(uri, "/")
+}
+
+func withQuery(url string, rawQuery string) string {
+	if rawQuery == "" {
+		return url
+	}
+	if strings.Contains(url, "?") {
+		return url + "&" + rawQuery
+	}
+	return url + "?" + rawQuery
+}
+
+// do 设置 ctx、追踪、超时 Header 和签名后发送请求
+func (c *Client) do(ctx context.Context, method string, url string, content []byte, contentType string, signer *secure.Signer, idempotencyKey string) (*Response, error) {
+	var body io.Reader
+	if content != nil {
+		body = bytes.NewReader(content)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	tracing.InjectHeader(ctx, req.Header)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if idempotencyKey != "" {
+		req.Header.Set(HeaderIdempotencyKey, idempotencyKey)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		remaining := time.Until(deadline).Milliseconds()
+		if remaining <= 0 {
+			return nil, context.DeadlineExceeded
+		}
+		req.Header.Set(haoxin.HeaderTimeout, strconv.FormatInt(remaining, 10))
+	}
+
+	if signer != nil {
+		if err := signer.Sign(req); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	r := &Response{StatusCode: resp.StatusCode, Header: resp.Header, Body: b}
+	if err != nil {
+		return r, err
+	}
+
+	return r, nil
+}
@@ -0,0 +1,56 @@
+package rpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// 对比共享连接池和每次调用新建 Transport（替换前 gorequest 的行为）
+//
+//	go test -run none -bench Client -benchmem ./haoxin/rpc
+func BenchmarkClientDo(b *testing.B) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":0,"msg":"ok","data":{"id":1}}`))
+	}))
+	defer server.Close()
+
+	query := map[string]interface{}{"id": 1, "name": "order"}
+	ctx := context.Background()
+
+	b.Run("pooled", func(b *testing.B) {
+		client := NewClient(ClientConfig{})
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := client.Do(ctx, http.MethodGet, server.URL, "/orders", query, nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("transport-per-call", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			transport := NewTransport(TransportConfig{})
+			client := NewClient(ClientConfig{Transport: transport})
+			if _, err := client.Do(ctx, http.MethodGet, server.URL, "/orders", query, nil); err != nil {
+				b.Fatal(err)
+			}
+			transport.CloseIdleConnections()
+		}
+	})
+
+	b.Run("pooled-parallel", func(b *testing.B) {
+		client := NewClient(ClientConfig{})
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				if _, err := client.Do(ctx, http.MethodGet, server.URL, "/orders", query, nil); err != nil {
+					b.Error(err)
+					return
+				}
+			}
+		})
+	})
+}
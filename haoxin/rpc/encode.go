@@ -0,0 +1,127 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+const (
+	contentTypeJSON = "application/json"
+	contentTypeForm = "application/x-www-form-urlencoded"
+)
+
+// encodeQuery 将 query 转为 URL 参数，与之前 gorequest 的行为一致：
+// 字符串为 JSON 对象或 a=1&b=2 格式，结构体和 map 转为 JSON 后取第一层字段，键转为小写
+func encodeQuery(query interface{}) (url.Values, error) {
+	switch q := query.(type) {
+	case nil:
+		return nil, nil
+	case url.Values:
+		return q, nil
+	case map[string][]string:
+		return q, nil
+	case string:
+		return queryString(q)
+	}
+
+	v := reflect.ValueOf(query)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return queryString(v.String())
+	case reflect.Struct, reflect.Map:
+		b, err := json.Marshal(v.Interface())
+		if err != nil {
+			return nil, err
+		}
+		var fields map[string]interface{}
+		d := json.NewDecoder(bytes.NewReader(b))
+		d.UseNumber()
+		if err := d.Decode(&fields); err != nil {
+			return nil, err
+		}
+
+		values := make(url.Values, len(fields))
+		for k, field := range fields {
+			k = strings.ToLower(k)
+			switch f := field.(type) {
+			case string:
+				values.Add(k, f)
+			case json.Number:
+				values.Add(k, f.String())
+			default:
+				j, err := json.Marshal(f)
+				if err != nil {
+					return nil, err
+				}
+				values.Add(k, string(j))
+			}
+		}
+		return values, nil
+	}
+
+	return nil, fmt.Errorf("不支持的 query 类型 %T", query)
+}
+
+func queryString(s string) (url.Values, error) {
+	var fields map[string]string
+	if err := json.Unmarshal([]byte(s), &fields); err == nil {
+		values := make(url.Values, len(fields))
+		for k, v := range fields {
+			values.Add(k, v)
+		}
+		return values, nil
+	}
+	return url.ParseQuery(s)
+}
+
+// encodeBody 将 body 转为请求内容，与之前 gorequest 的行为一致：
+// 字符串和 []byte 为 JSON 时原样发送，否则按表单发送，其他类型转为 JSON
+func encodeBody(body interface{}) ([]byte, string, error) {
+	switch b := body.(type) {
+	case nil:
+		return nil, "", nil
+	case string:
+		return bodyBytes([]byte(b))
+	case []byte:
+		return bodyBytes(b)
+	}
+
+	v := reflect.ValueOf(body)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, "", nil
+		}
+		v = v.Elem()
+	}
+
+	if v.Kind() == reflect.String {
+		return bodyBytes([]byte(v.String()))
+	}
+
+	b, err := json.Marshal(v.Interface())
+	if err != nil {
+		return nil, "", err
+	}
+	return b, contentTypeJSON, nil
+}
+
+func bodyBytes(b []byte) ([]byte, string, error) {
+	if len(b) == 0 {
+		return nil, "", nil
+	}
+	if json.Valid(b) {
+		return b, contentTypeJSON, nil
+	}
+	return b, contentTypeForm, nil
+}
@@ -0,0 +1,103 @@
+package rpc
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestJoinURL(t *testing.T) {
+	tests := []struct {
+		addr     string
+		uri      string
+		expected string
+	}{
+		{"http://127.0.0.1:8080", "/users", "http://127.0.0.1:8080/users"},
+		{"http://127.0.0.1:8080/", "/users", "http://127.0.0.1:8080/users"},
+		{"http://127.0.0.1:8080/", "users/", "http://127.0.0.1:8080/users/"},
+		{"http://127.0.0.1:8080/api", "users", "http://127.0.0.1:8080/api/users"},
+		{"http://127.0.0.1:8080", "?id=1", "http://127.0.0.1:8080?id=1"},
+		{"http://127.0.0.1:8080", "", "http://127.0.0.1:8080"},
+		{"", "http://127.0.0.1:8080/users", "http://127.0.0.1:8080/users"},
+	}
+	for _, tt := range tests {
+		if got := joinURL(tt.addr, tt.uri); got != tt.expected {
+			t.Errorf("joinURL(%s, %s) got = %s, expected %s", tt.addr, tt.uri, got, tt.expected)
+		}
+	}
+}
+
+func TestEncodeQuery(t *testing.T) {
+	type req struct {
+		Id    int    `json:"id"`
+		Name  string `json:"name"`
+		Empty string `json:"empty,omitempty"`
+		Tags  []string
+	}
+
+	tests := []struct {
+		query    interface{}
+		expected string
+	}{
+		{nil, ""},
+		{"a=1&b=2", "a=1&b=2"},
+		{`{"a":"1"}`, "a=1"},
+		{req{Id: 42, Name: "n", Tags: []string{"x"}}, "id=42&name=n&tags=%5B%22x%22%5D"},
+		{&req{Id: 12345678901234}, "id=12345678901234&name=&tags=null"},
+		{map[string]interface{}{"Page": 1}, "page=1"},
+	}
+	for _, tt := range tests {
+		values, err := encodeQuery(tt.query)
+		if err != nil || values.Encode() != tt.expected {
+			t.Errorf("encodeQuery(%v) got = %s, err = %v", tt.query, values.Encode(), err)
+		}
+	}
+
+	if _, err := encodeQuery(42); err == nil {
+		t.Errorf("encodeQuery(42) should fail")
+	}
+}
+
+func TestEncodeBody(t *testing.T) {
+	tests := []struct {
+		body        interface{}
+		expected    string
+		contentType string
+	}{
+		{nil, "", ""},
+		{"", "", ""},
+		{`{"a":1}`, `{"a":1}`, contentTypeJSON},
+		{[]byte(`[1,2]`), `[1,2]`, contentTypeJSON},
+		{"a=1&b=2", "a=1&b=2", contentTypeForm},
+		{map[string]int{"a": 1}, `{"a":1}`, contentTypeJSON},
+		{&struct{ Id int }{1}, `{"Id":1}`, contentTypeJSON},
+		{[]int{1, 2}, `[1,2]`, contentTypeJSON},
+	}
+	for _, tt := range tests {
+		b, contentType, err := encodeBody(tt.body)
+		if err != nil || string(b) != tt.expected || contentType != tt.contentType {
+			t.Errorf("encodeBody(%v) got = %s, %s, err = %v", tt.body, b, contentType, err)
+		}
+	}
+}
+
+func TestClientEncoding(t *testing.T) {
+	var r *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r = req
+		body, _ = ioutil.ReadAll(req.Body)
+	}))
+	defer server.Close()
+
+	client := NewClient(ClientConfig{})
+	_, err := client.Do(context.Background(), http.MethodPost, server.URL+"/", "/orders?source=app", map[string]string{"page": "2"}, map[string]int{"id": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.URL.Path != "/orders" || r.URL.RawQuery != "source=app&page=2" || r.Header.Get("Content-Type") != contentTypeJSON || string(body) != `{"id":1}` {
+		t.Errorf("request got = %s %s, %v, body = %s", r.URL.Path, r.URL.RawQuery, r.Header, body)
+	}
+}
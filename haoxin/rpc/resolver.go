@@ -0,0 +1,92 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// SchemeService 通过服务名调用时地址的前缀，例如 svc://USER-SVC/users/1
+const SchemeService = "svc://"
+
+var ErrServiceNotFound = errors.New("没有找到指定的服务")
+
+// Resolver 解析服务名对应的实例地址，地址格式为 http://host:port，
+// eureka.Eureka 也实现了该接口
+type Resolver interface {
+	Resolve(ctx context.Context, name string) ([]string, error)
+}
+
+// 默认的服务解析，Client 未设置 Resolver 时使用
+var defaultResolver Resolver
+
+// SetResolver 设置默认的服务解析，设置后 Rpc、RpcContext 可以使用 svc:// 地址
+func SetResolver(resolver Resolver) {
+	defaultResolver = resolver
+}
+
+// StaticResolver 固定的服务地址列表
+//
+// use it:
+//
+//	rpc.StaticResolver{"USER-SVC": {"http://10.0.0.1:8080", "http://10.0.0.2:8080"}}
+type StaticResolver map[string][]string
+
+func (r StaticResolver) Resolve(ctx context.Context, name string) ([]string, error) {
+	addrs := r[name]
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("%w：%s", ErrServiceNotFound, name)
+	}
+	return addrs, nil
+}
+
+// DNSResolver 通过 DNS 解析服务地址，服务名转为小写后加上 Suffix 作为域名，
+// 例如 Suffix 为 ".default.svc.cluster.local" 时 USER-SVC 解析 user-svc.default.svc.cluster.local
+type DNSResolver struct {
+	Scheme   string        // 默认 http
+	Port     int           // 默认 80
+	Suffix   string        // 域名后缀
+	Resolver *net.Resolver // 默认 net.DefaultResolver
+}
+
+func (r DNSResolver) Resolve(ctx context.Context, name string) ([]string, error) {
+	scheme := r.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	port := r.Port
+	if port <= 0 {
+		port = 80
+	}
+	resolver := r.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+
+	hosts, err := resolver.LookupHost(ctx, strings.ToLower(name)+r.Suffix)
+	if err != nil {
+		return nil, fmt.Errorf("%w：%s，%s", ErrServiceNotFound, name, err)
+	}
+
+	addrs := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		addrs = append(addrs, scheme+"://"+net.JoinHostPort(host, strconv.Itoa(port)))
+	}
+	return addrs, nil
+}
+
+// parseService 解析 svc://NAME/path，返回服务名和路径
+func parseService(s string) (name string, path string, ok bool) {
+	if !strings.HasPrefix(s, SchemeService) {
+		return "", "", false
+	}
+
+	s = s[len(SchemeService):]
+	if i := strings.Index(s, "/"); i >= 0 {
+		return s[:i], s[i:], s[:i] != ""
+	}
+	return s, "", s != ""
+}
@@ -0,0 +1,84 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestParseService(t *testing.T) {
+	tests := []struct {
+		s    string
+		name string
+		path string
+		ok   bool
+	}{
+		{"svc://USER-SVC/users/1", "USER-SVC", "/users/1", true},
+		{"svc://USER-SVC", "USER-SVC", "", true},
+		{"svc:///users", "", "", false},
+		{"http://127.0.0.1/users", "", "", false},
+	}
+	for _, tt := range tests {
+		name, path, ok := parseService(tt.s)
+		if ok != tt.ok || (ok && (name != tt.name || path != tt.path)) {
+			t.Errorf("parseService(%s) got = %s, %s, %v", tt.s, name, path, ok)
+		}
+	}
+}
+
+func TestDNSResolver(t *testing.T) {
+	addrs, err := DNSResolver{Port: 8080}.Resolve(context.Background(), "LOCALHOST")
+	if err != nil || len(addrs) == 0 {
+		t.Fatalf("Resolve() got = %v, err = %v", addrs, err)
+	}
+	found := false
+	for _, addr := range addrs {
+		found = found || addr == "http://127.0.0.1:8080"
+	}
+	if !found {
+		t.Errorf("Resolve() got = %v", addrs)
+	}
+}
+
+func TestClientResolver(t *testing.T) {
+	var hits [2]int32
+	newServer := func(i int, status int) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&hits[i], 1)
+			w.WriteHeader(status)
+			w.Write([]byte(r.URL.Path))
+		}))
+	}
+	down := newServer(0, http.StatusServiceUnavailable)
+	defer down.Close()
+	up := newServer(1, http.StatusOK)
+	defer up.Close()
+
+	client := NewClient(ClientConfig{Resolver: StaticResolver{"USER-SVC": {down.URL, up.URL}}})
+
+	// 第一次选到失败的实例后换一个实例重试
+	resp, err := client.Do(context.Background(), http.MethodGet, "", "svc://USER-SVC/users/1", nil, nil)
+	if err != nil || resp.String() != "/users/1" || atomic.LoadInt32(&hits[0]) != 1 || atomic.LoadInt32(&hits[1]) != 1 {
+		t.Fatalf("Do() got = %+v, err = %v, hits = %v", resp, err, hits)
+	}
+
+	// 失败的实例暂停选择
+	for i := 0; i < 3; i++ {
+		if resp, err := client.Do(context.Background(), http.MethodGet, "svc://USER-SVC", "/users/2", nil, nil); err != nil || resp.String() != "/users/2" {
+			t.Fatalf("Do() got = %+v, err = %v", resp, err)
+		}
+	}
+	if atomic.LoadInt32(&hits[0]) != 1 || atomic.LoadInt32(&hits[1]) != 4 {
+		t.Errorf("hits got = %v", hits)
+	}
+
+	if _, err := client.Do(context.Background(), http.MethodGet, "svc://ORDER-SVC", "/orders", nil, nil); !errors.Is(err, ErrServiceNotFound) {
+		t.Errorf("Do() err = %v", err)
+	}
+	if _, err := NewClient(ClientConfig{}).Do(context.Background(), http.MethodGet, "svc://USER-SVC", "/users", nil, nil); !errors.Is(err, ErrServiceNotFound) {
+		t.Errorf("Do() err = %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Response 服务的响应
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// OK 状态码是否为 2xx
+func (r *Response) OK() bool {
+	return r.StatusCode >= 200 && r.StatusCode < 300
+}
+
+func (r *Response) String() string {
+	return string(r.Body)
+}
+
+// 调用服务的错误类型，通过 errors.Is 判断
+var (
+	ErrTransport   = errors.New("调用服务出错")
+	ErrTimeout     = errors.New("调用服务超时")
+	ErrClient      = errors.New("服务返回客户端错误") // 4xx 以及其他非 2xx 状态码
+	ErrServer      = errors.New("服务返回服务端错误") // 5xx
+	ErrDecode      = errors.New("解析服务响应出错")
+	ErrCircuitOpen = errors.New("服务已熔断") // 熔断器拒绝调用，见 Breakers
+)
+
+// Error 调用服务的错误，Kind 为 ErrTransport、ErrTimeout、ErrClient、ErrServer、ErrDecode 或 ErrCircuitOpen
+type Error struct {
+	Kind     error
+	Method   string
+	URL      string
+	Response *Response // 收到响应时不为 nil
+	Err      error     // 网络错误、超时、解析等底层错误，状态码错误时为 nil
+}
+
+func newStatusError(method string, url string, resp *Response) *Error {
+	kind := ErrClient
+	if resp.StatusCode >= http.StatusInternalServerError {
+		kind = ErrServer
+	}
+	return &Error{Kind: kind, Method: method, URL: url, Response: resp}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s，%s %s，%s", e.Kind, e.Method, e.URL, e.Err)
+	}
+	return fmt.Sprintf("%s，%s %s，状态码 %d", e.Kind, e.Method, e.URL, e.Response.StatusCode)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
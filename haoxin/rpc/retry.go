@@ -0,0 +1,116 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// HeaderIdempotencyKey 幂等键 Header，设置后非幂等方法也会重试，见 OptionWithIdempotencyKey
+const HeaderIdempotencyKey = "Idempotency-Key"
+
+// RetryPolicy 重试策略，MaxAttempts 小于等于 1 时不重试。
+// 只重试传输错误、单次超时、熔断和 RetryOn 中的状态码，调用方取消 ctx 或 ctx 到期后不再重试。
+// 熔断只在按地址熔断（没有服务名）并且能换到其他地址时重试。
+// 默认只重试幂等方法（GET、HEAD、OPTIONS、PUT、DELETE、TRACE），其他方法需要设置幂等键
+type RetryPolicy struct {
+	MaxAttempts    int           // 最多调用次数，包含第一次
+	InitialBackoff time.Duration // 第一次重试前的等待时间，默认 100 毫秒
+	MaxBackoff     time.Duration // 最长等待时间，默认 2 秒
+	Multiplier     float64       // 每次重试等待时间的倍数，默认 2
+	Jitter         float64       // 等待时间随机浮动的比例，0 到 1，默认 0.2
+	RetryOn        []int         // 需要重试的状态码，默认 502、503、504
+}
+
+var (
+	jitterRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	jitterMux  sync.Mutex
+)
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = 100 * time.Millisecond
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = 2 * time.Second
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = 2
+	}
+	if p.Jitter <= 0 || p.Jitter > 1 {
+		p.Jitter = 0.2
+	}
+	if p.RetryOn == nil {
+		p.RetryOn = []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+	}
+	return p
+}
+
+// backoff 第 attempt 次调用失败后的等待时间，attempt 从 1 开始
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := float64(p.InitialBackoff) * math.Pow(p.Multiplier, float64(attempt-1))
+	if d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+
+	jitterMux.Lock()
+	r := jitterRand.Float64()
+	jitterMux.Unlock()
+
+	return time.Duration(d * (1 - p.Jitter + 2*p.Jitter*r))
+}
+
+// retryable 判断本次调用结果是否需要重试，ctx 为调用方传入的 ctx
+func (p RetryPolicy) retryable(ctx context.Context, resp *Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	if err != nil {
+		return errors.Is(err, ErrTransport) || errors.Is(err, ErrTimeout) || errors.Is(err, ErrCircuitOpen)
+	}
+
+	for _, code := range p.RetryOn {
+		if resp.StatusCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// sleep 等待 d，ctx 结束时提前返回 false
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// pickAddr 从 next 中选择一个没有失败过的地址，轮询 len(failed)+1 次都选到失败过的地址时使用最后一次的结果
+func pickAddr(next func() string, failed map[string]bool) string {
+	addr := ""
+	for i := 0; i <= len(failed); i++ {
+		addr = next()
+		if !failed[addr] {
+			break
+		}
+	}
+	return addr
+}
@@ -1,17 +1,44 @@
 package rpc
 
 import (
-	"errors"
-	"fmt"
+	"context"
 	"net/http"
-	"strings"
+	"time"
 
-	"github.com/parnurzeal/gorequest"
+	"github.com/chengjianxi/goc/haoxin"
+	"github.com/chengjianxi/goc/secure"
 )
 
+// 默认的请求签名，为 nil 时不签名
+var defaultSigner *secure.Signer
+
+// SetSigner 设置默认的请求签名，设置后所有调用都会自动签名
+func SetSigner(signer *secure.Signer) {
+	defaultSigner = signer
+}
+
+// 默认的熔断器，Client 未设置 Breakers 时使用，为 nil 时不熔断
+var defaultBreakers *Breakers
+
+// SetBreakers 设置默认的熔断器，设置后 Rpc、RpcContext 按服务名或地址熔断
+func SetBreakers(breakers *Breakers) {
+	defaultBreakers = breakers
+}
+
 type Option struct {
-	Sid string
-	Tid string
+	Sid            string
+	Tid            string
+	Signer         *secure.Signer
+	Timeout        time.Duration
+	CheckStatus    bool
+	Retry          *RetryPolicy
+	IdempotencyKey string
+	NextAddr       func() string
+	Service        string
+	Fallback       func(ctx context.Context, key string, err error) (*Response, error)
+
+	successCode *int
+	rawResponse bool
 }
 
 func OptionWithSid(sid string) Option {
@@ -22,59 +49,83 @@ func OptionWithTid(tid string) Option {
 	return Option{Tid: tid}
 }
 
-func Rpc(request *http.Request, method string, addr string, uri string, query interface{}, body interface{}, opts ...Option) (string, error) {
-	if addr == "" {
-		return "", errors.New("没有找到指定的服务")
-	}
+// OptionWithSigner 使用指定的签名代替默认签名
+func OptionWithSigner(signer *secure.Signer) Option {
+	return Option{Signer: signer}
+}
 
-	if addr != "" && strings.HasPrefix(addr, "/") {
-		// 服务地址后统一去掉 "/"
-		addr = addr[1:]
-	}
+// OptionWithTimeout 本次调用的超时时间，代替 Client 的默认超时
+func OptionWithTimeout(timeout time.Duration) Option {
+	return Option{Timeout: timeout}
+}
 
-	if uri != "" && !strings.HasPrefix(uri, "/") {
-		uri += "/"
-	}
+// OptionWithCheckStatus 非 2xx 响应返回 ErrClient 或 ErrServer
+func OptionWithCheckStatus() Option {
+	return Option{CheckStatus: true}
+}
 
-	url := fmt.Sprintf("%s%s", addr, uri)
-	userid := ""
-	machineid := ""
-	sid := ""
-	tid := ""
+// OptionWithSuccessCode Call 解析响应时表示成功的 code
+func OptionWithSuccessCode(code int) Option {
+	return Option{successCode: &code}
+}
 
-	for _, opt := range opts {
-		if opt.Sid != "" {
-			sid = opt.Sid
-		}
+// OptionWithRawResponse Call 不按 {code,msg,data} 解析，整个响应解析到 Resp
+func OptionWithRawResponse() Option {
+	return Option{rawResponse: true}
+}
 
-		if opt.Tid != "" {
-			tid = opt.Tid
-		}
-	}
+// OptionWithRetry 本次调用的重试策略，代替 Client 的默认策略
+func OptionWithRetry(policy RetryPolicy) Option {
+	return Option{Retry: &policy}
+}
+
+// OptionWithIdempotencyKey 设置幂等键 Header，POST 等非幂等方法设置后才会重试
+func OptionWithIdempotencyKey(key string) Option {
+	return Option{IdempotencyKey: key}
+}
+
+// OptionWithNextAddr 每次重试前通过 next 选择服务地址，跳过已经失败的实例，
+// addr 为空时第一次调用也通过 next 选择
+//
+// use it:
+//
+//	rpc.RpcContext(ctx, http.MethodGet, "", "/users/1", nil, nil, rpc.OptionWithNextAddr(func() string {
+//		return e.GetServiceAddress("USER-SVC")
+//	}))
+func OptionWithNextAddr(next func() string) Option {
+	return Option{NextAddr: next}
+}
+
+// OptionWithService 被调用的服务名，作为熔断器的 key，未设置时使用服务地址
+func OptionWithService(name string) Option {
+	return Option{Service: name}
+}
 
+// OptionWithFallback 熔断器拒绝调用时返回 fallback 的结果，代替 BreakerConfig.Fallback
+func OptionWithFallback(fallback func(ctx context.Context, key string, err error) (*Response, error)) Option {
+	return Option{Fallback: fallback}
+}
+
+// Rpc 使用 DefaultClient 调用服务，request 被取消时调用也会取消。
+// 只返回响应内容，需要状态码和 Header 时使用 Client.Do
+func Rpc(request *http.Request, method string, addr string, uri string, query interface{}, body interface{}, opts ...Option) (string, error) {
+	ctx := context.Background()
+	var m haoxin.Metadata
 	if request != nil {
-		userid = request.Header.Get("userid")
-		machineid = request.Header.Get("machineid")
-		if sid == "" {
-			sid = request.Header.Get("sid")
-		}
-		if tid == "" {
-			tid = request.Header.Get("tid")
-		}
+		ctx = request.Context()
+		m = haoxin.MetadataFromRequest(request)
 	}
+	return bodyString(DefaultClient.call(ctx, m, method, addr, uri, query, body, opts...))
+}
 
-	_, b, errs := gorequest.New().CustomMethod(method, url).
-		Set("sid", sid).
-		Set("tid", tid).
-		Set("userid", userid).
-		Set("machineid", machineid).
-		Query(query).
-		Send(body).
-		End()
-	if errs != nil {
-		errtxt := fmt.Sprintf("调用服务出错，%s", errs[0].Error())
-		return "", errors.New(errtxt)
-	}
+// RpcContext 与 Rpc 相同，sid、tid、userid、machineid 从 ctx 中获取，见 haoxin.NewContext
+func RpcContext(ctx context.Context, method string, addr string, uri string, query interface{}, body interface{}, opts ...Option) (string, error) {
+	return bodyString(DefaultClient.Do(ctx, method, addr, uri, query, body, opts...))
+}
 
-	return b, nil
+func bodyString(resp *Response, err error) (string, error) {
+	if err != nil {
+		return "", err
+	}
+	return resp.String(), nil
 }
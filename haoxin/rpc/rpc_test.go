@@ -0,0 +1,257 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/chengjianxi/goc/haoxin"
+	"github.com/chengjianxi/goc/haoxin/tracing"
+	"github.com/chengjianxi/goc/haoxin/tracing/tracingtest"
+	"github.com/chengjianxi/goc/secure"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestRpcContextTracing(t *testing.T) {
+	provider, exporter := tracingtest.NewInMemoryProvider()
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(trace.NewNoopTracerProvider())
+
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	sid := "4020230102030405123456"
+	ctx := haoxin.NewContext(context.Background(), haoxin.Metadata{Sid: sid, UserId: "u1"})
+	b, err := RpcContext(ctx, http.MethodGet, server.URL, "/orders", nil, nil)
+	if err != nil || b != "ok" {
+		t.Fatalf("RpcContext() got = %s, err = %v", b, err)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 || spans[0].SpanKind != trace.SpanKindClient {
+		t.Fatalf("spans got = %+v", spans)
+	}
+	traceID, _ := tracing.TraceIDFromSid(sid)
+	if spans[0].SpanContext.TraceID() != traceID {
+		t.Errorf("trace id got = %s", spans[0].SpanContext.TraceID())
+	}
+
+	traceparent := "00-" + traceID.String() + "-" + spans[0].SpanContext.SpanID().String() + "-01"
+	if header.Get("sid") != sid || header.Get("userid") != "u1" || header.Get("traceparent") != traceparent {
+		t.Errorf("header got = %v", header)
+	}
+}
+
+func TestClientSign(t *testing.T) {
+	// secure 默认签名的 Header 与 haoxin 传递请求信息的 Header 一致
+	headers := []string{haoxin.HeaderSid, haoxin.HeaderTid, haoxin.HeaderUserId, haoxin.HeaderMachineId}
+	if strings.Join(secure.DefaultSignedHeaders, ",") != strings.Join(headers, ",") {
+		t.Errorf("DefaultSignedHeaders got = %v", secure.DefaultSignedHeaders)
+	}
+
+	key := []byte("secret")
+	verifier := secure.NewVerifier(map[string][]byte{"svc": key}, secure.NewMemoryNonceStore())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 模拟中间人修改 userid
+		if r.URL.Path == "/tampered" {
+			r.Header.Set(haoxin.HeaderUserId, "admin")
+		}
+		if err := verifier.Verify(r); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(ClientConfig{Signer: secure.NewSigner("svc", key)})
+	ctx := haoxin.NewContext(context.Background(), haoxin.Metadata{Sid: "4020230102030405123456", UserId: "u1"})
+	for path, code := range map[string]int{"/orders": http.StatusOK, "/tampered": http.StatusUnauthorized} {
+		resp, err := client.Do(ctx, http.MethodPost, server.URL, path, nil, `{"id":1}`)
+		if err != nil || resp.StatusCode != code {
+			t.Errorf("Do(%s) got = %+v, err = %v", path, resp, err)
+		}
+	}
+}
+
+func TestClientTimeout(t *testing.T) {
+	release := make(chan struct{})
+	timeouts := make(chan string, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timeouts <- r.Header.Get(haoxin.HeaderTimeout)
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := NewClient(ClientConfig{Timeout: time.Hour})
+	start := time.Now()
+	_, err := client.Do(context.Background(), http.MethodGet, server.URL, "/slow", nil, nil, OptionWithTimeout(50*time.Millisecond))
+	if !errors.Is(err, ErrTimeout) || !errors.Is(err, context.DeadlineExceeded) || time.Since(start) > time.Second {
+		t.Errorf("Do() err = %v, elapsed %s", err, time.Since(start))
+	}
+	timeout := <-timeouts
+	if ms, _ := strconv.Atoi(timeout); ms <= 0 || ms > 50 {
+		t.Errorf("timeout header got = %q", timeout)
+	}
+
+	// 取消 ctx 时调用立即返回
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	if _, err := client.Do(ctx, http.MethodGet, server.URL, "/slow", nil, nil); !errors.Is(err, ErrTransport) || !errors.Is(err, context.Canceled) {
+		t.Errorf("Do() err = %v", err)
+	}
+}
+
+func TestClientStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/broken":
+			w.Header().Set("Content-Type", "text/html")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("<html>error</html>"))
+		default:
+			w.Write([]byte("ok"))
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(ClientConfig{})
+	resp, err := client.Do(context.Background(), http.MethodGet, server.URL, "/broken", nil, nil)
+	if err != nil || resp.StatusCode != http.StatusInternalServerError || resp.Header.Get("Content-Type") != "text/html" || resp.String() != "<html>error</html>" {
+		t.Errorf("Do() got = %+v, err = %v", resp, err)
+	}
+
+	tests := []struct {
+		uri      string
+		expected error
+	}{
+		{"/ok", nil},
+		{"/missing", ErrClient},
+		{"/broken", ErrServer},
+	}
+	for _, tt := range tests {
+		resp, err := client.Do(context.Background(), http.MethodGet, server.URL, tt.uri, nil, nil, OptionWithCheckStatus())
+		if !errors.Is(err, tt.expected) || (err == nil) != (tt.expected == nil) {
+			t.Errorf("Do(%s) err = %v, expected %v", tt.uri, err, tt.expected)
+		}
+		var rpcErr *Error
+		if errors.As(err, &rpcErr) && rpcErr.Response != resp {
+			t.Errorf("Do(%s) error response mismatch", tt.uri)
+		}
+	}
+
+	// 兼容以前的行为，不检查状态码
+	b, err := Rpc(nil, http.MethodGet, server.URL, "/broken", nil, nil)
+	if err != nil || b != "<html>error</html>" {
+		t.Errorf("Rpc() got = %s, err = %v", b, err)
+	}
+}
+
+func TestClientRetry(t *testing.T) {
+	var attempts int32
+	keys := make(chan string, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys <- r.Header.Get(HeaderIdempotencyKey)
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	client := NewClient(ClientConfig{Retry: RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}})
+	resp, err := client.Do(context.Background(), http.MethodGet, server.URL, "/orders", nil, nil)
+	if err != nil || resp.String() != "ok" || atomic.LoadInt32(&attempts) != 3 {
+		t.Errorf("Do() got = %+v, err = %v, attempts = %d", resp, err, attempts)
+	}
+
+	// 非幂等方法不重试
+	atomic.StoreInt32(&attempts, 0)
+	resp, err = client.Do(context.Background(), http.MethodPost, server.URL, "/orders", nil, nil, OptionWithCheckStatus())
+	if !errors.Is(err, ErrServer) || resp.StatusCode != http.StatusServiceUnavailable || atomic.LoadInt32(&attempts) != 1 {
+		t.Errorf("Do() got = %+v, err = %v, attempts = %d", resp, err, attempts)
+	}
+
+	// 设置幂等键后重试
+	atomic.StoreInt32(&attempts, 0)
+	for len(keys) > 0 {
+		<-keys
+	}
+	resp, err = client.Do(context.Background(), http.MethodPost, server.URL, "/orders", nil, nil, OptionWithIdempotencyKey("k1"))
+	if err != nil || resp.String() != "ok" || atomic.LoadInt32(&attempts) != 3 {
+		t.Errorf("Do() got = %+v, err = %v, attempts = %d", resp, err, attempts)
+	}
+	for i := 0; i < 3; i++ {
+		if key := <-keys; key != "k1" {
+			t.Errorf("idempotency key got = %q", key)
+		}
+	}
+
+	// 超过最多调用次数后返回最后一次的结果
+	atomic.StoreInt32(&attempts, -10)
+	resp, err = client.Do(context.Background(), http.MethodGet, server.URL, "/orders", nil, nil, OptionWithRetry(RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}))
+	if err != nil || resp.StatusCode != http.StatusServiceUnavailable || atomic.LoadInt32(&attempts) != -8 {
+		t.Errorf("Do() got = %+v, err = %v, attempts = %d", resp, err, attempts)
+	}
+}
+
+func TestClientRetryOtherInstance(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer down.Close()
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer up.Close()
+
+	// 轮询时跳过已经失败的实例
+	addrs := []string{down.URL, down.URL, up.URL}
+	var picked []string
+	next := func() string {
+		addr := addrs[len(picked)%len(addrs)]
+		picked = append(picked, addr)
+		return addr
+	}
+
+	client := NewClient(ClientConfig{Retry: RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}})
+	resp, err := client.Do(context.Background(), http.MethodGet, "", "/orders", nil, nil, OptionWithNextAddr(next))
+	if err != nil || resp.String() != "ok" || len(picked) != 3 {
+		t.Errorf("Do() got = %+v, err = %v, picked = %v", resp, err, picked)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	p := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}.withDefaults()
+	tests := []struct {
+		attempt  int
+		expected time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{4, 800 * time.Millisecond},
+		{10, time.Second},
+	}
+	for _, tt := range tests {
+		d := p.backoff(tt.attempt)
+		if d < tt.expected*8/10 || d > tt.expected*12/10 {
+			t.Errorf("backoff(%d) got = %s, expected %s ± 20%%", tt.attempt, d, tt.expected)
+		}
+	}
+}
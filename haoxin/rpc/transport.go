@@ -0,0 +1,64 @@
+package rpc
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"time"
+)
+
+// TransportConfig 连接池配置，未设置的字段使用默认值
+type TransportConfig struct {
+	MaxIdleConns        int           // 所有服务的最大空闲连接数，默认 100
+	MaxIdleConnsPerHost int           // 每个实例的最大空闲连接数，默认 32
+	MaxConnsPerHost     int           // 每个实例的最大连接数，默认不限制
+	IdleConnTimeout     time.Duration // 空闲连接的关闭时间，默认 90 秒
+	DialTimeout         time.Duration // 建立连接的超时时间，默认 5 秒
+	KeepAlive           time.Duration // TCP keep-alive 间隔，默认 30 秒
+	TLSHandshakeTimeout time.Duration // TLS 握手超时时间，默认 10 秒
+	TLSClientConfig     *tls.Config   // 调用 https 服务时的 TLS 配置
+	DisableKeepAlives   bool          // 每次调用后关闭连接
+	DisableHTTP2        bool          // 不尝试 HTTP/2，默认 https 服务协商使用 HTTP/2
+}
+
+// 未设置 ClientConfig.Transport 的 Client 共享同一个连接池
+var defaultTransport = NewTransport(TransportConfig{})
+
+// NewTransport 创建连接池，用于 ClientConfig.Transport
+func NewTransport(cfg TransportConfig) *http.Transport {
+	if cfg.MaxIdleConns <= 0 {
+		cfg.MaxIdleConns = 100
+	}
+	if cfg.MaxIdleConnsPerHost <= 0 {
+		cfg.MaxIdleConnsPerHost = 32
+	}
+	if cfg.IdleConnTimeout <= 0 {
+		cfg.IdleConnTimeout = 90 * time.Second
+	}
+	if cfg.DialTimeout <= 0 {
+		cfg.DialTimeout = 5 * time.Second
+	}
+	if cfg.KeepAlive <= 0 {
+		cfg.KeepAlive = 30 * time.Second
+	}
+	if cfg.TLSHandshakeTimeout <= 0 {
+		cfg.TLSHandshakeTimeout = 10 * time.Second
+	}
+
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   cfg.DialTimeout,
+			KeepAlive: cfg.KeepAlive,
+		}).DialContext,
+		MaxIdleConns:          cfg.MaxIdleConns,
+		MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       cfg.MaxConnsPerHost,
+		IdleConnTimeout:       cfg.IdleConnTimeout,
+		TLSHandshakeTimeout:   cfg.TLSHandshakeTimeout,
+		TLSClientConfig:       cfg.TLSClientConfig,
+		DisableKeepAlives:     cfg.DisableKeepAlives,
+		ForceAttemptHTTP2:     !cfg.DisableHTTP2,
+		ExpectContinueTimeout: time.Second,
+	}
+}
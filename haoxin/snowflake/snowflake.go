@@ -0,0 +1,134 @@
+package snowflake
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MaxBits worker id 和序号的位数之和的最大值，时间戳至少保留 41 位，从 Epoch 起约 69 年
+const MaxBits = 22
+
+var (
+	ErrClockBackwards  = errors.New("系统时钟回拨超过允许范围")
+	ErrInvalidWorkerId = errors.New("worker id 超出范围")
+	ErrInvalidBits     = fmt.Errorf("worker id 和序号的位数之和必须在 1 到 %d 之间", MaxBits)
+	ErrInvalidEpoch    = errors.New("起始时间不能晚于当前时间")
+	ErrTimeOverflow    = errors.New("时间戳超出 ID 可表示的范围")
+)
+
+// DefaultEpoch 默认起始时间
+var DefaultEpoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Options 生成器配置，ID 由高到低依次为：毫秒时间戳、worker id、序号，
+// worker id 和序号的位数之和不超过 MaxBits
+type Options struct {
+	Epoch        time.Time     // 起始时间，默认 DefaultEpoch，设置后不能再修改，否则可能重复
+	WorkerBits   uint8         // worker id 位数，与 SequenceBits 都为 0 时默认 10，最多 1024 个实例
+	SequenceBits uint8         // 每毫秒序号位数，与 WorkerBits 都为 0 时默认 12，每毫秒最多 4096 个 ID
+	MaxRollback  time.Duration // 时钟回拨不超过此时间时等待时钟追上，超过时返回 ErrClockBackwards，默认 10 毫秒
+}
+
+// Generator 64 位有序 ID 生成器，不同实例必须使用不同的 worker id，
+// 可以通过 cache.AcquireWorkerId 或 eureka.Eureka.WorkerId 分配
+type Generator struct {
+	epoch       int64 // 毫秒
+	workerId    int64
+	workerBits  uint8
+	seqBits     uint8
+	maxRollback time.Duration
+
+	mux    sync.Mutex
+	lastMs int64
+	seq    int64
+	now    func() time.Time
+}
+
+// New 创建生成器，workerId 必须小于 MaxWorkerId(opts)+1
+func New(workerId int64, opts Options) (*Generator, error) {
+	if opts.Epoch.IsZero() {
+		opts.Epoch = DefaultEpoch
+	}
+	if opts.WorkerBits == 0 && opts.SequenceBits == 0 {
+		opts.WorkerBits, opts.SequenceBits = 10, 12
+	}
+	if opts.WorkerBits+opts.SequenceBits == 0 || opts.WorkerBits+opts.SequenceBits > MaxBits {
+		return nil, ErrInvalidBits
+	}
+	if opts.Epoch.After(time.Now()) {
+		return nil, ErrInvalidEpoch
+	}
+	if opts.MaxRollback <= 0 {
+		opts.MaxRollback = 10 * time.Millisecond
+	}
+	if workerId < 0 || workerId > MaxWorkerId(opts) {
+		return nil, ErrInvalidWorkerId
+	}
+
+	return &Generator{
+		epoch:       opts.Epoch.UnixMilli(),
+		workerId:    workerId,
+		workerBits:  opts.WorkerBits,
+		seqBits:     opts.SequenceBits,
+		maxRollback: opts.MaxRollback,
+		lastMs:      -1,
+		now:         time.Now,
+	}, nil
+}
+
+// MaxWorkerId 返回配置允许的最大 worker id
+func MaxWorkerId(opts Options) int64 {
+	if opts.WorkerBits == 0 && opts.SequenceBits == 0 {
+		opts.WorkerBits = 10
+	}
+	return 1<<opts.WorkerBits - 1
+}
+
+// NextID 生成 ID，同一毫秒内序号用完时等待下一毫秒
+func (g *Generator) NextID() (int64, error) {
+	g.mux.Lock()
+	defer g.mux.Unlock()
+
+	ms := g.millis()
+	if ms >= 1<<(63-g.workerBits-g.seqBits) {
+		return 0, ErrTimeOverflow
+	}
+	if ms < g.lastMs {
+		backwards := time.Duration(g.lastMs-ms) * time.Millisecond
+		if backwards > g.maxRollback {
+			return 0, fmt.Errorf("%w，回拨 %s", ErrClockBackwards, backwards)
+		}
+		time.Sleep(backwards)
+		if ms = g.millis(); ms < g.lastMs {
+			return 0, fmt.Errorf("%w，回拨 %s", ErrClockBackwards, time.Duration(g.lastMs-ms)*time.Millisecond)
+		}
+	}
+
+	if ms == g.lastMs {
+		g.seq = (g.seq + 1) & (1<<g.seqBits - 1)
+		if g.seq == 0 {
+			for ms <= g.lastMs {
+				time.Sleep(100 * time.Microsecond)
+				ms = g.millis()
+			}
+		}
+	} else {
+		g.seq = 0
+	}
+	g.lastMs = ms
+
+	return ms<<(g.workerBits+g.seqBits) | g.workerId<<g.seqBits | g.seq, nil
+}
+
+func (g *Generator) millis() int64 {
+	return g.now().UnixMilli() - g.epoch
+}
+
+// Parse 解析 ID 中的生成时间、worker id 和序号
+func (g *Generator) Parse(id int64) (t time.Time, workerId int64, seq int64) {
+	ms := id >> (g.workerBits + g.seqBits)
+	workerId = id >> g.seqBits & (1<<g.workerBits - 1)
+	seq = id & (1<<g.seqBits - 1)
+	return time.UnixMilli(g.epoch + ms), workerId, seq
+}
@@ -0,0 +1,110 @@
+package snowflake
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNextID(t *testing.T) {
+	g, err := New(5, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := DefaultEpoch.Add(time.Hour)
+	g.now = func() time.Time { return now }
+
+	var last int64
+	for i := 0; i < 4096; i++ {
+		id, err := g.NextID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id <= last {
+			t.Fatalf("id %d not greater than %d", id, last)
+		}
+		last = id
+	}
+
+	ts, workerId, seq := g.Parse(last)
+	if !ts.Equal(now) || workerId != 5 || seq != 4095 {
+		t.Errorf("Parse() got = %v, %d, %d", ts, workerId, seq)
+	}
+
+	// 序号用完时等待下一毫秒
+	calls := 0
+	g.now = func() time.Time {
+		if calls++; calls > 3 {
+			return now.Add(time.Millisecond)
+		}
+		return now
+	}
+	id, err := g.NextID()
+	now = now.Add(time.Millisecond)
+	if ts, _, seq := g.Parse(id); err != nil || !ts.Equal(now) || seq != 0 {
+		t.Errorf("NextID() after overflow got = %v, %d, err = %v", ts, seq, err)
+	}
+}
+
+func TestClockRollback(t *testing.T) {
+	g, _ := New(1, Options{WorkerBits: 4, SequenceBits: 4, MaxRollback: 5 * time.Millisecond})
+	now := DefaultEpoch.Add(time.Hour)
+	g.now = func() time.Time { return now }
+	g.NextID()
+
+	now = now.Add(-time.Second)
+	if _, err := g.NextID(); !errors.Is(err, ErrClockBackwards) {
+		t.Errorf("NextID() err = %v", err)
+	}
+
+	// 小幅回拨时等待时钟追上
+	now = now.Add(time.Second - 2*time.Millisecond)
+	calls := 0
+	g.now = func() time.Time {
+		calls++
+		if calls > 1 {
+			return now.Add(2 * time.Millisecond)
+		}
+		return now
+	}
+	if _, err := g.NextID(); err != nil {
+		t.Errorf("NextID() err = %v", err)
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	if _, err := New(16, Options{WorkerBits: 4, SequenceBits: 8}); err != ErrInvalidWorkerId {
+		t.Errorf("New() err = %v", err)
+	}
+	if _, err := New(0, Options{WorkerBits: 20, SequenceBits: 12}); err != ErrInvalidBits {
+		t.Errorf("New() err = %v", err)
+	}
+	if _, err := New(0, Options{WorkerBits: 16, SequenceBits: 15}); err != ErrInvalidBits {
+		t.Errorf("New() err = %v", err)
+	}
+	if _, err := New(0, Options{Epoch: time.Now().Add(time.Hour)}); err != ErrInvalidEpoch {
+		t.Errorf("New() err = %v", err)
+	}
+	if MaxWorkerId(Options{}) != 1023 {
+		t.Errorf("MaxWorkerId() got = %d", MaxWorkerId(Options{}))
+	}
+}
+
+func TestTimeOverflow(t *testing.T) {
+	g, err := New(1, Options{WorkerBits: 12, SequenceBits: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 41 位毫秒时间戳约 69 年后用完
+	now := DefaultEpoch.Add(time.Duration(1<<41-1) * time.Millisecond)
+	g.now = func() time.Time { return now }
+	if id, err := g.NextID(); err != nil || id <= 0 {
+		t.Errorf("NextID() got = %d, err = %v", id, err)
+	}
+
+	now = now.Add(time.Millisecond)
+	if id, err := g.NextID(); err != ErrTimeOverflow {
+		t.Errorf("NextID() got = %d, err = %v", id, err)
+	}
+}
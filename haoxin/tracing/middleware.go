@@ -0,0 +1,63 @@
+package tracing
+
+import (
+	"github.com/chengjianxi/goc/haoxin"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Middleware 返回 gin 中间件，上游可以只传 traceparent/tracestate 或只传 sid/tid，
+// 都没有时使用 haoxin.NewSid(moduleId) 生成 sid。请求信息保存到请求的 context 中，
+// 并为每个请求创建 server span，可以代替 haoxin.Middleware，模块 ID 不正确时 panic
+//
+// use it:
+//
+//	otel.SetTracerProvider(provider)
+//	router.Use(tracing.Middleware("40"))
+func Middleware(moduleId string) gin.HandlerFunc {
+	if err := haoxin.ValidateModuleId(moduleId); err != nil {
+		panic(err)
+	}
+
+	return func(c *gin.Context) {
+		ctx := Propagator{}.Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+		m, _ := haoxin.FromContext(ctx)
+		if m.Sid == "" {
+			m.Sid = haoxin.NewSid(moduleId)
+			ctx = haoxin.NewContext(ctx, m)
+		}
+		// 兼容从请求 Header 中读取 sid 的旧代码
+		if c.Request.Header.Get(haoxin.HeaderSid) == "" {
+			c.Request.Header.Set(haoxin.HeaderSid, m.Sid)
+		}
+		if !trace.SpanContextFromContext(ctx).IsValid() {
+			if sc, err := SpanContextFromMetadata(m); err == nil {
+				ctx = trace.ContextWithRemoteSpanContext(ctx, sc)
+			}
+		}
+
+		route := c.FullPath()
+		if route == "" {
+			route = c.Request.URL.Path
+		}
+		ctx, span := tracer().Start(ctx, c.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPMethodKey.String(c.Request.Method),
+				semconv.HTTPTargetKey.String(c.Request.URL.RequestURI()),
+				semconv.HTTPRouteKey.String(route),
+			))
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		var err error
+		if len(c.Errors) > 0 {
+			err = c.Errors.Last()
+		}
+		EndSpan(span, c.Writer.Status(), err)
+	}
+}
@@ -0,0 +1,240 @@
+package tracing
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/chengjianxi/goc/haoxin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// TraceStateKey tracestate 中保存 sid/tid 的键，值的格式为 sid:<sid>;tid:<tid>
+	TraceStateKey = "haoxin"
+
+	instrumentationName = "github.com/chengjianxi/goc/haoxin/tracing"
+)
+
+var errNotHaoxinTraceID = errors.New("trace id 不是由 sid 生成的")
+
+// 由 sid 生成的 trace id 以此结尾，用于识别
+var sidTraceMagic = [5]byte{'h', 'a', 'o', 'x', 'n'}
+
+// TraceIDFromSid 将 sid 编码为 trace id，可以通过 SidFromTraceID 还原：
+// 模块(2 字节)+时间(6 字节)+序号(3 字节)+"haoxn"
+func TraceIDFromSid(sid string) (trace.TraceID, error) {
+	info, err := haoxin.ParseSid(sid)
+	if err != nil {
+		return trace.TraceID{}, err
+	}
+	t, _ := strconv.ParseUint(sid[2:16], 10, 64)
+
+	var id trace.TraceID
+	copy(id[:2], info.ModuleId)
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], t)
+	copy(id[2:8], b[2:])
+	binary.BigEndian.PutUint32(b[:4], uint32(info.Seq))
+	copy(id[8:11], b[1:4])
+	copy(id[11:], sidTraceMagic[:])
+	return id, nil
+}
+
+// SidFromTraceID 还原由 TraceIDFromSid 生成的 trace id，其他 trace id 返回错误
+func SidFromTraceID(id trace.TraceID) (string, error) {
+	if string(id[11:]) != string(sidTraceMagic[:]) {
+		return "", errNotHaoxinTraceID
+	}
+
+	var b [8]byte
+	copy(b[2:], id[2:8])
+	t := binary.BigEndian.Uint64(b[:])
+	b = [8]byte{}
+	copy(b[1:4], id[8:11])
+	seq := binary.BigEndian.Uint32(b[:4])
+
+	sid := fmt.Sprintf("%s%014d%06d", id[:2], t, seq)
+	if _, err := haoxin.ParseSid(sid); err != nil {
+		return "", errNotHaoxinTraceID
+	}
+	return sid, nil
+}
+
+// SpanContextFromMetadata 由 sid/tid 生成远程的 span context，用于没有 traceparent 的上游，
+// span id 由 tid（没有时由 sid）计算
+func SpanContextFromMetadata(m haoxin.Metadata) (trace.SpanContext, error) {
+	traceID, err := TraceIDFromSid(m.Sid)
+	if err != nil {
+		return trace.SpanContext{}, err
+	}
+
+	h := fnv.New64a()
+	if m.Tid != "" {
+		h.Write([]byte(m.Tid))
+	} else {
+		h.Write([]byte(m.Sid))
+	}
+	var spanID trace.SpanID
+	binary.BigEndian.PutUint64(spanID[:], h.Sum64()|1)
+
+	state, err := withMetadata(trace.TraceState{}, m)
+	if err != nil {
+		return trace.SpanContext{}, err
+	}
+
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+		TraceState: state,
+		Remote:     true,
+	}), nil
+}
+
+func withMetadata(state trace.TraceState, m haoxin.Metadata) (trace.TraceState, error) {
+	var values []string
+	if m.Sid != "" {
+		values = append(values, "sid:"+m.Sid)
+	}
+	if m.Tid != "" {
+		values = append(values, "tid:"+m.Tid)
+	}
+	if len(values) == 0 {
+		return state, nil
+	}
+	return state.Insert(TraceStateKey, strings.Join(values, ";"))
+}
+
+func metadataFromTraceState(state trace.TraceState) (sid string, tid string) {
+	for _, value := range strings.Split(state.Get(TraceStateKey), ";") {
+		if v := strings.TrimPrefix(value, "sid:"); v != value {
+			sid = v
+		} else if v := strings.TrimPrefix(value, "tid:"); v != value {
+			tid = v
+		}
+	}
+	return sid, tid
+}
+
+// Propagator 同时传递 W3C traceparent/tracestate 和 sid/tid/userid/machineid Header，
+// 可以通过 otel.SetTextMapPropagator 设置为全局的 propagator
+type Propagator struct{}
+
+var _ propagation.TextMapPropagator = Propagator{}
+
+// Inject 写入 ctx 中的 span context 和 haoxin.Metadata，没有 span context 时由 sid 生成
+func (p Propagator) Inject(ctx context.Context, carrier propagation.TextMapCarrier) {
+	m, _ := haoxin.FromContext(ctx)
+
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() && m.Sid != "" {
+		sc, _ = SpanContextFromMetadata(m)
+	}
+	if sc.IsValid() {
+		if state, err := withMetadata(sc.TraceState(), m); err == nil {
+			sc = sc.WithTraceState(state)
+		}
+		propagation.TraceContext{}.Inject(trace.ContextWithSpanContext(ctx, sc), carrier)
+	}
+
+	if m.Sid == "" && sc.IsValid() {
+		m.Sid, _ = SidFromTraceID(sc.TraceID())
+	}
+	for k, v := range map[string]string{
+		haoxin.HeaderSid:       m.Sid,
+		haoxin.HeaderTid:       m.Tid,
+		haoxin.HeaderUserId:    m.UserId,
+		haoxin.HeaderMachineId: m.MachineId,
+	} {
+		if v != "" {
+			carrier.Set(k, v)
+		}
+	}
+}
+
+// Extract 读取 traceparent/tracestate 和 sid/tid Header，缺少的一方由另一方补全，
+// 结果保存到返回的 context 中，见 haoxin.FromContext
+func (p Propagator) Extract(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	ctx = propagation.TraceContext{}.Extract(ctx, carrier)
+	sc := trace.SpanContextFromContext(ctx)
+
+	m := haoxin.Metadata{
+		Sid:       carrier.Get(haoxin.HeaderSid),
+		Tid:       carrier.Get(haoxin.HeaderTid),
+		UserId:    carrier.Get(haoxin.HeaderUserId),
+		MachineId: carrier.Get(haoxin.HeaderMachineId),
+	}
+	sid, tid := metadataFromTraceState(sc.TraceState())
+	if m.Sid == "" {
+		m.Sid = sid
+	}
+	if m.Sid == "" && sc.IsValid() {
+		m.Sid, _ = SidFromTraceID(sc.TraceID())
+	}
+	if m.Tid == "" {
+		m.Tid = tid
+	}
+
+	if !sc.IsValid() && m.Sid != "" {
+		if sc, err := SpanContextFromMetadata(m); err == nil {
+			ctx = trace.ContextWithRemoteSpanContext(ctx, sc)
+		}
+	}
+	if m != (haoxin.Metadata{}) {
+		ctx = haoxin.NewContext(ctx, m)
+	}
+	return ctx
+}
+
+func (p Propagator) Fields() []string {
+	return append(propagation.TraceContext{}.Fields(),
+		haoxin.HeaderSid, haoxin.HeaderTid, haoxin.HeaderUserId, haoxin.HeaderMachineId)
+}
+
+func tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// StartClientSpan 开始调用其他服务的 span，ctx 中没有 span 时以 sid 生成的 trace id 作为父级
+func StartClientSpan(ctx context.Context, method string, url string) (context.Context, trace.Span) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		if m, ok := haoxin.FromContext(ctx); ok {
+			if sc, err := SpanContextFromMetadata(m); err == nil {
+				ctx = trace.ContextWithRemoteSpanContext(ctx, sc)
+			}
+		}
+	}
+
+	return tracer().Start(ctx, "HTTP "+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.HTTPMethodKey.String(method), semconv.HTTPURLKey.String(url)))
+}
+
+// InjectHeader 将 ctx 中的 trace 和请求信息写入 Header
+func InjectHeader(ctx context.Context, header http.Header) {
+	Propagator{}.Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+// EndSpan 记录响应状态码和错误后结束 span，statusCode 为 0 表示没有响应
+func EndSpan(span trace.Span, statusCode int, err error) {
+	if statusCode != 0 {
+		span.SetAttributes(semconv.HTTPStatusCodeKey.Int(statusCode))
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else if statusCode >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(statusCode))
+	}
+	span.End()
+}
@@ -0,0 +1,114 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/chengjianxi/goc/haoxin"
+	"github.com/chengjianxi/goc/haoxin/tracing/tracingtest"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const testSid = "4020230102030405123456"
+
+func TestTraceIDFromSid(t *testing.T) {
+	id, err := TraceIDFromSid(testSid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sid, err := SidFromTraceID(id)
+	if err != nil || sid != testSid {
+		t.Errorf("SidFromTraceID() got = %s, err = %v", sid, err)
+	}
+
+	random, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	if _, err := SidFromTraceID(random); err == nil {
+		t.Errorf("SidFromTraceID() should fail for random trace id")
+	}
+	if _, err := TraceIDFromSid("bad"); err == nil {
+		t.Errorf("TraceIDFromSid() should fail for invalid sid")
+	}
+}
+
+func TestPropagatorFromSid(t *testing.T) {
+	ctx := haoxin.NewContext(context.Background(), haoxin.Metadata{Sid: testSid, Tid: "t1", UserId: "u1"})
+	header := http.Header{}
+	InjectHeader(ctx, header)
+
+	traceID, _ := TraceIDFromSid(testSid)
+	sc := trace.SpanContextFromContext(propagation.TraceContext{}.Extract(context.Background(), propagation.HeaderCarrier(header)))
+	if sc.TraceID() != traceID || header.Get("tracestate") != "haoxin=sid:"+testSid+";tid:t1" {
+		t.Errorf("header got = %v", header)
+	}
+	if header.Get(haoxin.HeaderSid) != testSid || header.Get(haoxin.HeaderUserId) != "u1" {
+		t.Errorf("header got = %v", header)
+	}
+
+	// 只有 traceparent/tracestate 时还原 sid/tid
+	header.Del(haoxin.HeaderSid)
+	header.Del(haoxin.HeaderTid)
+	m, _ := haoxin.FromContext(Propagator{}.Extract(context.Background(), propagation.HeaderCarrier(header)))
+	if m.Sid != testSid || m.Tid != "t1" || m.UserId != "u1" {
+		t.Errorf("metadata got = %+v", m)
+	}
+}
+
+func TestPropagatorFromTraceparent(t *testing.T) {
+	header := http.Header{}
+	header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	ctx := Propagator{}.Extract(context.Background(), propagation.HeaderCarrier(header))
+	if _, ok := haoxin.FromContext(ctx); ok {
+		t.Errorf("random trace id should not produce metadata")
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("span context got = %v", sc)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	provider, exporter := tracingtest.NewInMemoryProvider()
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(trace.NewNoopTracerProvider())
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware("40"))
+
+	var m haoxin.Metadata
+	var sc trace.SpanContext
+	router.GET("/orders/:id", func(c *gin.Context) {
+		m, _ = haoxin.FromContext(c)
+		sc = trace.SpanContextFromContext(c.Request.Context())
+		c.Status(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/orders/1", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	// 上游只传 traceparent 时生成 sid，trace id 保持不变
+	if len(m.Sid) != 22 || sc.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("metadata got = %+v, span context = %v", m, sc)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/orders/2", nil)
+	req.Header.Set(haoxin.HeaderSid, testSid)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	traceID, _ := TraceIDFromSid(testSid)
+	if m.Sid != testSid || sc.TraceID() != traceID {
+		t.Errorf("metadata got = %+v, span context = %v", m, sc)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 || spans[1].Name != "GET /orders/:id" || spans[1].SpanKind != trace.SpanKindServer ||
+		spans[1].Status.Description != "Internal Server Error" {
+		t.Errorf("spans got = %+v", spans)
+	}
+}
@@ -0,0 +1,13 @@
+package tracingtest
+
+import (
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// NewInMemoryProvider 创建将 span 保存在内存中的 TracerProvider，用于测试和本地调试，
+// 需要通过 otel.SetTracerProvider 设置为全局。依赖 OpenTelemetry SDK，与 tracing 分开避免使用 tracing 的服务链接 SDK
+func NewInMemoryProvider() (*sdktrace.TracerProvider, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	return sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)), exporter
+}
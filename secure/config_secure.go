@@ -7,12 +7,14 @@ import (
 	"encoding/base64"
 	"io"
 	"regexp"
-
-	"golang.org/x/crypto/scrypt"
 )
 
+var encryptedConfItemRegexp = regexp.MustCompile(`^S\(([A-Za-z0-9+/=]+)\.([A-Za-z0-9+/=]+)(?:;([a-z0-9]+(?::[0-9]+)*))?\)$`)
+
 // 判断一个字符串是 `S(cryp.salt)` 格式的，并提取括号中的内容
 // 其中 aaaa 是加密后的密文，bbbb 为密钥 salt
+//
+// 带派生参数的 `S(cryp.salt;params)` 格式请使用 ParseEncryptedConfItem
 func IsEncryptedConfItem(str string) (bool, string, string) {
 	// 定义正则表达式，匹配 S(base64.base64) 格式
 	re, err := regexp.Compile(`^S\(([A-Za-z0-9+/=]+)\.([A-Za-z0-9+/=]+)\)$`)
@@ -60,7 +62,7 @@ func RandomSalt(length int) (string, error) {
 // error - 如果派生密钥过程中发生错误，则返回非nil的error
 func DeriveKey(password string, salt string) ([]byte, error) {
 	// derived key for e.g. AES-256 (which needs a 32-byte key)
-	return scrypt.Key([]byte(password), []byte(salt), 32768, 8, 1, 32)
+	return DeriveKeyWithParams(password, salt, DefaultKDFParams)
 }
 
 // Encrypt 函数用于将明文进行AES加密，并返回加密后的密文字符串
@@ -124,8 +126,45 @@ func EncryptConfItem(str string, password string, salt string) (string, error) {
 	return "S(" + cipherText + "." + saltText + ")", nil
 }
 
+// EncryptConfItemWithParams 使用指定的派生参数加密配置项，
+// 返回 `S(cryp.salt;params)` 格式，解密时自动按 params 派生密钥
+func EncryptConfItemWithParams(str string, password string, salt string, params KDFParams) (string, error) {
+	key, err := DeriveKeyWithParams(password, salt, params)
+	if err != nil {
+		return "", err
+	}
+
+	cipherText, err := Encrypt([]byte(str), key)
+	if err != nil {
+		return "", err
+	}
+
+	saltText := base64.StdEncoding.EncodeToString([]byte(salt))
+	return "S(" + cipherText + "." + saltText + ";" + params.String() + ")", nil
+}
+
+// ParseEncryptedConfItem 解析 `S(cryp.salt)` 或 `S(cryp.salt;params)` 格式的配置项
+// 没有携带派生参数时返回 DefaultKDFParams
+func ParseEncryptedConfItem(str string) (bool, string, string, KDFParams) {
+	matches := encryptedConfItemRegexp.FindStringSubmatch(str)
+	if len(matches) == 0 {
+		return false, "", "", KDFParams{}
+	}
+
+	params := DefaultKDFParams
+	if matches[3] != "" {
+		var err error
+		params, err = ParseKDFParams(matches[3])
+		if err != nil {
+			return false, "", "", KDFParams{}
+		}
+	}
+
+	return true, matches[1], matches[2], params
+}
+
 func DecryptIfEncryptedConfItem(str string, password string) string {
-	isEncrypted, cryp, salt := IsEncryptedConfItem(str)
+	isEncrypted, cryp, salt, params := ParseEncryptedConfItem(str)
 	if !isEncrypted {
 		return str
 	}
@@ -135,7 +174,7 @@ func DecryptIfEncryptedConfItem(str string, password string) string {
 		return str
 	}
 
-	key, err := DeriveKey(password, string(saltData))
+	key, err := DeriveKeyWithParams(password, string(saltData), params)
 	if err != nil {
 		return str
 	}
@@ -0,0 +1,234 @@
+package secure
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+)
+
+// KeySet JWT 密钥集合，按 kid 查找，可从 JWKS 文件重新加载以轮换密钥
+type KeySet struct {
+	keys map[string]JWTKey
+	mux  sync.RWMutex
+}
+
+func NewKeySet(keys ...JWTKey) *KeySet {
+	k := &KeySet{keys: make(map[string]JWTKey)}
+	for _, key := range keys {
+		k.keys[key.Id] = key
+	}
+	return k
+}
+
+// Find 按 kid 和算法查找密钥；kid 为空时仅在该算法只有一个密钥时返回
+func (k *KeySet) Find(kid string, algorithm string) (JWTKey, bool) {
+	k.mux.RLock()
+	defer k.mux.RUnlock()
+
+	if kid != "" {
+		key, has := k.keys[kid]
+		if !has || key.Algorithm != algorithm {
+			return JWTKey{}, false
+		}
+		return key, true
+	}
+
+	var found JWTKey
+	count := 0
+	for _, key := range k.keys {
+		if key.Algorithm == algorithm {
+			found = key
+			count++
+		}
+	}
+	return found, count == 1
+}
+
+// Replace 使用新的密钥替换全部密钥
+func (k *KeySet) Replace(keys ...JWTKey) {
+	m := make(map[string]JWTKey)
+	for _, key := range keys {
+		m[key.Id] = key
+	}
+
+	k.mux.Lock()
+	defer k.mux.Unlock()
+
+	k.keys = m
+}
+
+// ReloadFile 从 JWKS 文件重新加载密钥，加载失败时保留原有密钥
+func (k *KeySet) ReloadFile(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	keys, err := ParseJWKS(data)
+	if err != nil {
+		return err
+	}
+
+	k.Replace(keys...)
+	return nil
+}
+
+// WatchFile 每隔 interval 检查 JWKS 文件，修改后重新加载；返回的函数用于停止检查
+// 重新加载的错误通过 onError 回调，可为 nil
+func (k *KeySet) WatchFile(path string, interval time.Duration, onError func(error)) func() {
+	stop := make(chan struct{})
+	var modTime time.Time
+	if info, err := os.Stat(path); err == nil {
+		modTime = info.ModTime()
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				info, err := os.Stat(path)
+				if err == nil && info.ModTime().Equal(modTime) {
+					continue
+				}
+				if err == nil {
+					modTime = info.ModTime()
+					err = k.ReloadFile(path)
+				}
+				if err != nil && onError != nil {
+					onError(err)
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(stop) })
+	}
+}
+
+// LoadJWKSFile 从 JWKS 文件加载密钥集合
+func LoadJWKSFile(path string) (*KeySet, error) {
+	keys := NewKeySet()
+	if err := keys.ReloadFile(path); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv"`
+	K   string `json:"k"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	D   string `json:"d"`
+	P   string `json:"p"`
+	Q   string `json:"q"`
+	X   string `json:"x"`
+}
+
+// ParseJWKS 解析 JWKS，支持 oct(HS256)、RSA(RS256)、OKP Ed25519(EdDSA)，
+// 包含私钥参数时解析为私钥，可用于签发 token
+func ParseJWKS(data []byte) ([]JWTKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make([]JWTKey, 0, len(set.Keys))
+	for _, k := range set.Keys {
+		key, err := parseJWK(k)
+		if err != nil {
+			return nil, fmt.Errorf("解析密钥 %q 出错，%w", k.Kid, err)
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+func parseJWK(k jwk) (JWTKey, error) {
+	decode := base64.RawURLEncoding.DecodeString
+
+	switch k.Kty {
+	case "oct":
+		secret, err := decode(k.K)
+		if err != nil || len(secret) == 0 {
+			return JWTKey{}, fmt.Errorf("参数 k 不合法")
+		}
+		return JWTKey{Id: k.Kid, Algorithm: jwkAlgorithm(k.Alg, JWTHS256), Key: secret}, nil
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil || len(n) == 0 {
+			return JWTKey{}, fmt.Errorf("参数 n 不合法")
+		}
+		e, err := decode(k.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return JWTKey{}, fmt.Errorf("参数 e 不合法")
+		}
+		pub := rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		if k.D == "" {
+			return JWTKey{Id: k.Kid, Algorithm: jwkAlgorithm(k.Alg, JWTRS256), Key: &pub}, nil
+		}
+
+		d, errD := decode(k.D)
+		p, errP := decode(k.P)
+		q, errQ := decode(k.Q)
+		if errD != nil || errP != nil || errQ != nil {
+			return JWTKey{}, fmt.Errorf("私钥参数不合法")
+		}
+		priv := &rsa.PrivateKey{
+			PublicKey: pub,
+			D:         new(big.Int).SetBytes(d),
+			Primes:    []*big.Int{new(big.Int).SetBytes(p), new(big.Int).SetBytes(q)},
+		}
+		if err := priv.Validate(); err != nil {
+			return JWTKey{}, err
+		}
+		priv.Precompute()
+		return JWTKey{Id: k.Kid, Algorithm: jwkAlgorithm(k.Alg, JWTRS256), Key: priv}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return JWTKey{}, fmt.Errorf("不支持的曲线 %q", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return JWTKey{}, fmt.Errorf("参数 x 不合法")
+		}
+		if k.D == "" {
+			return JWTKey{Id: k.Kid, Algorithm: jwkAlgorithm(k.Alg, JWTEdDSA), Key: ed25519.PublicKey(x)}, nil
+		}
+
+		seed, err := decode(k.D)
+		if err != nil || len(seed) != ed25519.SeedSize {
+			return JWTKey{}, fmt.Errorf("参数 d 不合法")
+		}
+		return JWTKey{Id: k.Kid, Algorithm: jwkAlgorithm(k.Alg, JWTEdDSA), Key: ed25519.NewKeyFromSeed(seed)}, nil
+	}
+
+	return JWTKey{}, fmt.Errorf("不支持的密钥类型 %q", k.Kty)
+}
+
+func jwkAlgorithm(alg string, defaultAlg string) string {
+	if alg == "" {
+		return defaultAlg
+	}
+	return alg
+}
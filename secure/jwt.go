@@ -0,0 +1,308 @@
+package secure
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// JWT 签名算法
+const (
+	JWTHS256 = "HS256"
+	JWTRS256 = "RS256"
+	JWTEdDSA = "EdDSA"
+)
+
+// Token 类型，写入 Claims.TokenType，防止 refresh token 被当作 access token 使用
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
+var (
+	ErrTokenMalformed    = errors.New("token 格式错误")
+	ErrTokenSignature    = errors.New("token 签名错误")
+	ErrTokenExpired      = errors.New("token 已过期")
+	ErrTokenNotValidYet  = errors.New("token 尚未生效")
+	ErrTokenInvalidClaim = errors.New("token 内容不合法")
+	ErrTokenKeyNotFound  = errors.New("未找到 token 签名密钥")
+)
+
+// Claims JWT 载荷，userid、machineid 与请求 Header 中的字段保持一致
+type Claims struct {
+	UserId    string `json:"userid,omitempty"`
+	MachineId string `json:"machineid,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+
+	Issuer    string `json:"iss,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	Audience  string `json:"aud,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	NotBefore int64  `json:"nbf,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	Id        string `json:"jti,omitempty"`
+}
+
+// JWTKey JWT 签名密钥，Key 的类型取决于算法：
+// HS256 为 []byte，RS256 为 *rsa.PrivateKey 或 *rsa.PublicKey，
+// EdDSA 为 ed25519.PrivateKey 或 ed25519.PublicKey；签发 token 需要私钥
+type JWTKey struct {
+	Id        string
+	Algorithm string
+	Key       interface{}
+}
+
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ,omitempty"`
+	KeyId     string `json:"kid,omitempty"`
+}
+
+// SignJWT 使用指定密钥签发 token
+func SignJWT(claims Claims, key JWTKey) (string, error) {
+	header, err := json.Marshal(jwtHeader{Algorithm: key.Algorithm, Type: "JWT", KeyId: key.Id})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	var signature []byte
+	switch k := key.Key.(type) {
+	case []byte:
+		if key.Algorithm != JWTHS256 {
+			return "", fmt.Errorf("密钥与算法 %s 不匹配", key.Algorithm)
+		}
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signingInput))
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		if key.Algorithm != JWTRS256 {
+			return "", fmt.Errorf("密钥与算法 %s 不匹配", key.Algorithm)
+		}
+		signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		if err != nil {
+			return "", err
+		}
+	case ed25519.PrivateKey:
+		if key.Algorithm != JWTEdDSA {
+			return "", fmt.Errorf("密钥与算法 %s 不匹配", key.Algorithm)
+		}
+		signature = ed25519.Sign(k, []byte(signingInput))
+	default:
+		return "", fmt.Errorf("不支持使用 %T 签发 token", key.Key)
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// 使用密钥校验签名，签名算法必须与密钥算法一致，防止算法混淆攻击
+func verifyJWTSignature(signingInput string, signature []byte, key JWTKey) bool {
+	switch key.Algorithm {
+	case JWTHS256:
+		k, ok := key.Key.([]byte)
+		if !ok {
+			return false
+		}
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signingInput))
+		return hmac.Equal(signature, mac.Sum(nil))
+	case JWTRS256:
+		var pub *rsa.PublicKey
+		switch k := key.Key.(type) {
+		case *rsa.PublicKey:
+			pub = k
+		case *rsa.PrivateKey:
+			pub = &k.PublicKey
+		default:
+			return false
+		}
+		digest := sha256.Sum256([]byte(signingInput))
+		return rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature) == nil
+	case JWTEdDSA:
+		var pub ed25519.PublicKey
+		switch k := key.Key.(type) {
+		case ed25519.PublicKey:
+			pub = k
+		case ed25519.PrivateKey:
+			pub = k.Public().(ed25519.PublicKey)
+		default:
+			return false
+		}
+		return ed25519.Verify(pub, []byte(signingInput), signature)
+	}
+	return false
+}
+
+// TokenPair 登录或刷新时签发的一对 token
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"` // access token 有效期，秒
+}
+
+// TokenIssuer 签发 access/refresh token
+type TokenIssuer struct {
+	Key        JWTKey
+	Issuer     string
+	Audience   string // 不为空时写入 aud
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+	Now        func() time.Time // 用于测试，默认 time.Now
+}
+
+// NewTokenIssuer 创建 TokenIssuer，默认 access token 有效期 2 小时，refresh token 有效期 7 天
+func NewTokenIssuer(key JWTKey, issuer string) *TokenIssuer {
+	return &TokenIssuer{
+		Key:        key,
+		Issuer:     issuer,
+		AccessTTL:  2 * time.Hour,
+		RefreshTTL: 7 * 24 * time.Hour,
+		Now:        time.Now,
+	}
+}
+
+// Issue 为用户签发 access/refresh token
+func (i *TokenIssuer) Issue(userId string, machineId string) (*TokenPair, error) {
+	now := time.Now
+	if i.Now != nil {
+		now = i.Now
+	}
+	issuedAt := now()
+
+	issue := func(tokenType string, ttl time.Duration) (string, error) {
+		id := make([]byte, 16)
+		if _, err := io.ReadFull(rand.Reader, id); err != nil {
+			return "", err
+		}
+
+		return SignJWT(Claims{
+			UserId:    userId,
+			MachineId: machineId,
+			TokenType: tokenType,
+			Issuer:    i.Issuer,
+			Audience:  i.Audience,
+			Subject:   userId,
+			ExpiresAt: issuedAt.Add(ttl).Unix(),
+			IssuedAt:  issuedAt.Unix(),
+			Id:        hex.EncodeToString(id),
+		}, i.Key)
+	}
+
+	access, err := issue(TokenTypeAccess, i.AccessTTL)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := issue(TokenTypeRefresh, i.RefreshTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{AccessToken: access, RefreshToken: refresh, ExpiresIn: int64(i.AccessTTL / time.Second)}, nil
+}
+
+// Refresh 校验 refresh token 并签发新的一对 token
+func (i *TokenIssuer) Refresh(refreshToken string, verifier *TokenVerifier) (*TokenPair, error) {
+	claims, err := verifier.Verify(refreshToken, TokenTypeRefresh)
+	if err != nil {
+		return nil, err
+	}
+
+	return i.Issue(claims.UserId, claims.MachineId)
+}
+
+// TokenVerifier 校验 token，密钥从 KeySet 中按 kid 查找，支持密钥轮换
+type TokenVerifier struct {
+	Keys     *KeySet
+	Issuer   string        // 不为空时校验 iss
+	Audience string        // 不为空时校验 aud
+	Leeway   time.Duration // 允许的时间偏差
+	Now      func() time.Time
+}
+
+func NewTokenVerifier(keys *KeySet, issuer string) *TokenVerifier {
+	return &TokenVerifier{Keys: keys, Issuer: issuer, Leeway: time.Minute, Now: time.Now}
+}
+
+// Verify 校验 token 签名、有效期、签发者、接收方和类型，tokenType 为空时不校验类型，
+// 没有 exp 的 token 永不过期，返回 ErrTokenInvalidClaim
+func (v *TokenVerifier) Verify(token string, tokenType string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrTokenMalformed
+	}
+
+	var header jwtHeader
+	if err := decodeJWTSegment(parts[0], &header); err != nil {
+		return nil, ErrTokenMalformed
+	}
+
+	key, has := v.Keys.Find(header.KeyId, header.Algorithm)
+	if !has {
+		return nil, ErrTokenKeyNotFound
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrTokenMalformed
+	}
+	if !verifyJWTSignature(parts[0]+"."+parts[1], signature, key) {
+		return nil, ErrTokenSignature
+	}
+
+	var claims Claims
+	if err := decodeJWTSegment(parts[1], &claims); err != nil {
+		return nil, ErrTokenMalformed
+	}
+
+	now := time.Now
+	if v.Now != nil {
+		now = v.Now
+	}
+	ts := now().Unix()
+	leeway := int64(v.Leeway / time.Second)
+	if claims.ExpiresAt == 0 {
+		return nil, ErrTokenInvalidClaim
+	}
+	if ts > claims.ExpiresAt+leeway {
+		return nil, ErrTokenExpired
+	}
+	if claims.NotBefore != 0 && ts < claims.NotBefore-leeway {
+		return nil, ErrTokenNotValidYet
+	}
+	if v.Issuer != "" && claims.Issuer != v.Issuer {
+		return nil, ErrTokenInvalidClaim
+	}
+	if v.Audience != "" && claims.Audience != v.Audience {
+		return nil, ErrTokenInvalidClaim
+	}
+	if tokenType != "" && claims.TokenType != tokenType {
+		return nil, ErrTokenInvalidClaim
+	}
+
+	return &claims, nil
+}
+
+func decodeJWTSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,141 @@
+package secure
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTokenIssueAndVerify(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	keys := []JWTKey{
+		{Id: "hs", Algorithm: JWTHS256, Key: []byte("secret")},
+		{Id: "rs", Algorithm: JWTRS256, Key: rsaKey},
+		{Id: "ed", Algorithm: JWTEdDSA, Key: edKey},
+	}
+
+	for _, key := range keys {
+		t.Run(key.Algorithm, func(t *testing.T) {
+			issuer := NewTokenIssuer(key, "haoxin")
+			verifier := NewTokenVerifier(NewKeySet(keys...), "haoxin")
+
+			pair, err := issuer.Issue("10001", "m1")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			claims, err := verifier.Verify(pair.AccessToken, TokenTypeAccess)
+			if err != nil {
+				t.Fatalf("Verify() err = %v", err)
+			}
+			if claims.UserId != "10001" || claims.MachineId != "m1" {
+				t.Errorf("Verify() claims = %+v", claims)
+			}
+
+			if _, err := verifier.Verify(pair.RefreshToken, TokenTypeAccess); err != ErrTokenInvalidClaim {
+				t.Errorf("refresh token used as access token err = %v", err)
+			}
+
+			if _, err := issuer.Refresh(pair.RefreshToken, verifier); err != nil {
+				t.Errorf("Refresh() err = %v", err)
+			}
+
+			verifier.Now = func() time.Time { return time.Now().Add(3 * time.Hour) }
+			if _, err := verifier.Verify(pair.AccessToken, TokenTypeAccess); err != ErrTokenExpired {
+				t.Errorf("expired token err = %v", err)
+			}
+		})
+	}
+}
+
+func TestTokenVerifyClaims(t *testing.T) {
+	key := JWTKey{Id: "hs", Algorithm: JWTHS256, Key: []byte("secret")}
+	exp := time.Now().Add(time.Hour).Unix()
+
+	tests := []struct {
+		name     string
+		claims   Claims
+		expected error
+	}{
+		{"valid", Claims{UserId: "1", TokenType: TokenTypeAccess, Audience: "app", ExpiresAt: exp}, nil},
+		{"missing exp", Claims{UserId: "1", TokenType: TokenTypeAccess, Audience: "app"}, ErrTokenInvalidClaim},
+		{"wrong audience", Claims{UserId: "1", TokenType: TokenTypeAccess, Audience: "admin", ExpiresAt: exp}, ErrTokenInvalidClaim},
+		{"missing audience", Claims{UserId: "1", TokenType: TokenTypeAccess, ExpiresAt: exp}, ErrTokenInvalidClaim},
+	}
+
+	verifier := NewTokenVerifier(NewKeySet(key), "")
+	verifier.Audience = "app"
+	for _, tt := range tests {
+		token, _ := SignJWT(tt.claims, key)
+		if _, err := verifier.Verify(token, TokenTypeAccess); err != tt.expected {
+			t.Errorf("%s: Verify() err = %v, expected %v", tt.name, err, tt.expected)
+		}
+	}
+
+	issuer := NewTokenIssuer(key, "")
+	issuer.Audience = "app"
+	pair, _ := issuer.Issue("1", "m1")
+	if _, err := verifier.Verify(pair.AccessToken, TokenTypeAccess); err != nil {
+		t.Errorf("Verify() err = %v", err)
+	}
+}
+
+func TestTokenRejectsAlgorithmConfusion(t *testing.T) {
+	// 使用 HS256 伪造 kid 为 RS256 公钥的 token
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	keys := NewKeySet(JWTKey{Id: "rs", Algorithm: JWTRS256, Key: &rsaKey.PublicKey})
+	forged, _ := SignJWT(Claims{UserId: "1"}, JWTKey{Id: "rs", Algorithm: JWTHS256, Key: []byte("public")})
+
+	if _, err := NewTokenVerifier(keys, "").Verify(forged, ""); err != ErrTokenKeyNotFound {
+		t.Errorf("Verify() err = %v, expected %v", err, ErrTokenKeyNotFound)
+	}
+}
+
+func TestJWKSRotation(t *testing.T) {
+	encode := base64.RawURLEncoding.EncodeToString
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	jwks := fmt.Sprintf(`{"keys":[
+		{"kty":"oct","kid":"k1","k":%q},
+		{"kty":"OKP","kid":"k2","crv":"Ed25519","x":%q,"d":%q},
+		{"kty":"RSA","kid":"k3","n":%q,"e":%q}
+	]}`, encode([]byte("secret1")), encode(pub), encode(priv.Seed()),
+		encode(rsaKey.N.Bytes()), encode(big.NewInt(int64(rsaKey.E)).Bytes()))
+	ioutil.WriteFile(path, []byte(jwks), 0644)
+
+	keys, err := LoadJWKSFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier := NewTokenVerifier(keys, "")
+
+	claims := Claims{UserId: "1", ExpiresAt: time.Now().Add(time.Hour).Unix()}
+	edToken, _ := SignJWT(claims, JWTKey{Id: "k2", Algorithm: JWTEdDSA, Key: priv})
+	rsToken, _ := SignJWT(claims, JWTKey{Id: "k3", Algorithm: JWTRS256, Key: rsaKey})
+	hsToken, _ := SignJWT(claims, JWTKey{Id: "k1", Algorithm: JWTHS256, Key: []byte("secret1")})
+	for _, token := range []string{edToken, rsToken, hsToken} {
+		if _, err := verifier.Verify(token, ""); err != nil {
+			t.Errorf("Verify() err = %v", err)
+		}
+	}
+
+	// 轮换密钥后旧密钥签发的 token 失效
+	ioutil.WriteFile(path, []byte(fmt.Sprintf(`{"keys":[{"kty":"oct","kid":"k4","k":%q}]}`, encode([]byte("secret2")))), 0644)
+	if err := keys.ReloadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifier.Verify(hsToken, ""); err != ErrTokenKeyNotFound {
+		t.Errorf("Verify() err = %v, expected %v", err, ErrTokenKeyNotFound)
+	}
+}
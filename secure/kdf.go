@@ -0,0 +1,217 @@
+package secure
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// 密钥派生算法
+const (
+	KDFScrypt   = "scrypt"
+	KDFArgon2id = "argon2id"
+)
+
+// 派生密钥长度，AES-256 需要 32 字节
+const derivedKeyLength = 32
+
+// 派生密钥缓存的最大条目数，超过后淘汰最久未使用的
+const maxDerivedKeyCacheSize = 1024
+
+// 派生参数的上限，参数来自加密配置项，过大的参数会在解密时占用大量内存和 CPU
+const (
+	maxScryptN       = 1 << 20
+	maxScryptR       = 32
+	maxScryptP       = 16
+	maxScryptMemory  = 1 << 30 // 128*N*r 字节
+	maxArgon2Time    = 16
+	maxArgon2Memory  = 1 << 20 // KiB，即 1 GiB
+	maxArgon2Threads = 64
+)
+
+// KDFParams 密钥派生参数，会编码进加密配置项中，解密时按原参数派生密钥
+type KDFParams struct {
+	Algorithm string // 算法，KDFScrypt 或 KDFArgon2id
+
+	// scrypt 参数
+	N int // CPU/内存开销，必须是大于 1 的 2 的幂
+	R int // 块大小
+	P int // 并行度
+
+	// argon2id 参数
+	Time    uint32 // 迭代次数
+	Memory  uint32 // 内存开销，单位 KiB
+	Threads uint8  // 并行线程数
+}
+
+// DefaultKDFParams 默认派生参数，与历史版本 DeriveKey 保持一致
+var DefaultKDFParams = ScryptParams(32768, 8, 1)
+
+// ScryptParams 返回 scrypt 派生参数
+func ScryptParams(n, r, p int) KDFParams {
+	return KDFParams{Algorithm: KDFScrypt, N: n, R: r, P: p}
+}
+
+// Argon2idParams 返回 argon2id 派生参数，memory 单位为 KiB
+func Argon2idParams(time, memory uint32, threads uint8) KDFParams {
+	return KDFParams{Algorithm: KDFArgon2id, Time: time, Memory: memory, Threads: threads}
+}
+
+// Validate 校验派生参数是否合法，并限制参数上限：scrypt N 不超过 2^20、内存不超过 1 GiB，argon2id memory 不超过 1 GiB
+func (p KDFParams) Validate() error {
+	switch p.Algorithm {
+	case KDFScrypt:
+		if p.N <= 1 || p.N&(p.N-1) != 0 {
+			return errors.New("scrypt 参数 N 必须是大于 1 的 2 的幂")
+		}
+		if p.R <= 0 || p.P <= 0 {
+			return errors.New("scrypt 参数 r、p 必须大于 0")
+		}
+		if p.N > maxScryptN || p.R > maxScryptR || p.P > maxScryptP || 128*int64(p.N)*int64(p.R) > maxScryptMemory {
+			return fmt.Errorf("scrypt 参数超出上限，N 不能超过 %d，r 不能超过 %d，p 不能超过 %d，128*N*r 不能超过 1 GiB", maxScryptN, maxScryptR, maxScryptP)
+		}
+	case KDFArgon2id:
+		if p.Time == 0 || p.Threads == 0 {
+			return errors.New("argon2id 参数 time、threads 必须大于 0")
+		}
+		if p.Memory < 8*uint32(p.Threads) {
+			return errors.New("argon2id 参数 memory 不能小于 8*threads")
+		}
+		if p.Time > maxArgon2Time || p.Memory > maxArgon2Memory || p.Threads > maxArgon2Threads {
+			return fmt.Errorf("argon2id 参数超出上限，time 不能超过 %d，memory 不能超过 %d KiB，threads 不能超过 %d", maxArgon2Time, maxArgon2Memory, maxArgon2Threads)
+		}
+	default:
+		return fmt.Errorf("不支持的密钥派生算法 %q", p.Algorithm)
+	}
+	return nil
+}
+
+// String 将派生参数编码为文本，格式为 scrypt:N:r:p 或 argon2id:time:memory:threads
+func (p KDFParams) String() string {
+	switch p.Algorithm {
+	case KDFScrypt:
+		return fmt.Sprintf("%s:%d:%d:%d", p.Algorithm, p.N, p.R, p.P)
+	case KDFArgon2id:
+		return fmt.Sprintf("%s:%d:%d:%d", p.Algorithm, p.Time, p.Memory, p.Threads)
+	}
+	return p.Algorithm
+}
+
+// ParseKDFParams 解析 KDFParams.String 生成的文本
+func ParseKDFParams(s string) (KDFParams, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 4 {
+		return KDFParams{}, fmt.Errorf("密钥派生参数格式错误 %q", s)
+	}
+
+	values := make([]uint64, 3)
+	for i, part := range parts[1:] {
+		v, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			return KDFParams{}, fmt.Errorf("密钥派生参数格式错误 %q", s)
+		}
+		values[i] = v
+	}
+
+	var params KDFParams
+	switch parts[0] {
+	case KDFScrypt:
+		params = ScryptParams(int(values[0]), int(values[1]), int(values[2]))
+	case KDFArgon2id:
+		if values[2] > 255 {
+			return KDFParams{}, fmt.Errorf("密钥派生参数格式错误 %q", s)
+		}
+		params = Argon2idParams(uint32(values[0]), uint32(values[1]), uint8(values[2]))
+	default:
+		return KDFParams{}, fmt.Errorf("不支持的密钥派生算法 %q", parts[0])
+	}
+
+	if err := params.Validate(); err != nil {
+		return KDFParams{}, err
+	}
+	return params, nil
+}
+
+// 派生密钥缓存，key 为 sha256(password, salt, params)，避免明文密码常驻内存，
+// order 按使用时间排序，最近使用的在前
+var derivedKeyCache = struct {
+	keys  map[string]*list.Element
+	order *list.List
+	mux   sync.Mutex
+}{keys: make(map[string]*list.Element), order: list.New()}
+
+type derivedKeyEntry struct {
+	cacheKey string
+	key      []byte
+}
+
+func derivedKeyCacheKey(password string, salt string, params KDFParams) string {
+	h := sha256.New()
+	for _, s := range []string{password, salt, params.String()} {
+		h.Write([]byte(strconv.Itoa(len(s))))
+		h.Write([]byte{0})
+		h.Write([]byte(s))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// DeriveKeyWithParams 使用指定参数从密码和盐值中派生密钥
+//
+// 相同的 (password, salt, params) 只会真正计算一次，后续直接从缓存返回，
+// 启动时批量解密使用相同密码的配置项时可以避免重复的高开销计算。
+func DeriveKeyWithParams(password string, salt string, params KDFParams) ([]byte, error) {
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+
+	cacheKey := derivedKeyCacheKey(password, salt, params)
+	derivedKeyCache.mux.Lock()
+	if e, has := derivedKeyCache.keys[cacheKey]; has {
+		derivedKeyCache.order.MoveToFront(e)
+		key := append([]byte(nil), e.Value.(*derivedKeyEntry).key...)
+		derivedKeyCache.mux.Unlock()
+		return key, nil
+	}
+	derivedKeyCache.mux.Unlock()
+
+	var key []byte
+	var err error
+	switch params.Algorithm {
+	case KDFScrypt:
+		key, err = scrypt.Key([]byte(password), []byte(salt), params.N, params.R, params.P, derivedKeyLength)
+	case KDFArgon2id:
+		key = argon2.IDKey([]byte(password), []byte(salt), params.Time, params.Memory, params.Threads, derivedKeyLength)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	derivedKeyCache.mux.Lock()
+	if _, has := derivedKeyCache.keys[cacheKey]; !has {
+		derivedKeyCache.keys[cacheKey] = derivedKeyCache.order.PushFront(&derivedKeyEntry{cacheKey: cacheKey, key: key})
+		if derivedKeyCache.order.Len() > maxDerivedKeyCacheSize {
+			oldest := derivedKeyCache.order.Remove(derivedKeyCache.order.Back()).(*derivedKeyEntry)
+			delete(derivedKeyCache.keys, oldest.cacheKey)
+		}
+	}
+	derivedKeyCache.mux.Unlock()
+
+	return append([]byte(nil), key...), nil
+}
+
+// ClearDerivedKeyCache 清空派生密钥缓存
+func ClearDerivedKeyCache() {
+	derivedKeyCache.mux.Lock()
+	defer derivedKeyCache.mux.Unlock()
+
+	derivedKeyCache.keys = make(map[string]*list.Element)
+	derivedKeyCache.order.Init()
+}
@@ -0,0 +1,142 @@
+package secure
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+)
+
+func TestParseKDFParams(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected KDFParams
+		wantErr  bool
+	}{
+		{
+			name:     "scrypt",
+			input:    "scrypt:16384:8:1",
+			expected: ScryptParams(16384, 8, 1),
+		},
+		{
+			name:     "argon2id",
+			input:    "argon2id:1:65536:4",
+			expected: Argon2idParams(1, 65536, 4),
+		},
+		{
+			name:    "scrypt N not power of two",
+			input:   "scrypt:1000:8:1",
+			wantErr: true,
+		},
+		{
+			name:    "scrypt N too large",
+			input:   "scrypt:2097152:8:1",
+			wantErr: true,
+		},
+		{
+			name:    "scrypt memory too large",
+			input:   "scrypt:1048576:16:1",
+			wantErr: true,
+		},
+		{
+			name:    "argon2id memory too large",
+			input:   "argon2id:1:4294967295:255",
+			wantErr: true,
+		},
+		{
+			name:    "argon2id time too large",
+			input:   "argon2id:100:65536:4",
+			wantErr: true,
+		},
+		{
+			name:    "unknown algorithm",
+			input:   "pbkdf2:1:2:3",
+			wantErr: true,
+		},
+		{
+			name:    "missing fields",
+			input:   "scrypt:16384",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseKDFParams(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseKDFParams() err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.expected {
+				t.Errorf("ParseKDFParams() got = %v, expected %v", got, tt.expected)
+			}
+			if !tt.wantErr && got.String() != tt.input {
+				t.Errorf("String() got = %v, expected %v", got.String(), tt.input)
+			}
+		})
+	}
+}
+
+func TestDeriveKeyWithParamsCache(t *testing.T) {
+	ClearDerivedKeyCache()
+
+	params := ScryptParams(1024, 8, 1)
+	key1, err := DeriveKeyWithParams("password", "salt", params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 修改返回值不能影响缓存
+	key1[0] ^= 0xff
+	key2, _ := DeriveKeyWithParams("password", "salt", params)
+	if bytes.Equal(key1, key2) {
+		t.Errorf("cached key should not be shared with caller")
+	}
+
+	key3, _ := DeriveKeyWithParams("password", "salt", ScryptParams(2048, 8, 1))
+	if bytes.Equal(key2, key3) {
+		t.Errorf("different params should derive different keys")
+	}
+}
+
+func TestDerivedKeyCacheEviction(t *testing.T) {
+	ClearDerivedKeyCache()
+	defer ClearDerivedKeyCache()
+
+	params := ScryptParams(2, 1, 1)
+	cached := func(salt string) bool {
+		derivedKeyCache.mux.Lock()
+		defer derivedKeyCache.mux.Unlock()
+		_, has := derivedKeyCache.keys[derivedKeyCacheKey("password", salt, params)]
+		return has
+	}
+
+	for i := 0; i < maxDerivedKeyCacheSize; i++ {
+		DeriveKeyWithParams("password", strconv.Itoa(i), params)
+	}
+	// 最早的条目被使用后不会被淘汰，超过上限时只淘汰最久未使用的一个
+	DeriveKeyWithParams("password", "0", params)
+	DeriveKeyWithParams("password", "new", params)
+	if !cached("0") || cached("1") || !cached("2") || !cached("new") || len(derivedKeyCache.keys) != maxDerivedKeyCacheSize {
+		t.Errorf("cache size got = %d", len(derivedKeyCache.keys))
+	}
+}
+
+func TestConfItemWithParams(t *testing.T) {
+	for _, params := range []KDFParams{ScryptParams(1024, 8, 1), Argon2idParams(1, 1024, 1)} {
+		salt, _ := RandomSalt(8)
+		cipherText, err := EncryptConfItemWithParams("abc#$%^&*d", "pass", salt, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		isEncrypted, _, _, got := ParseEncryptedConfItem(cipherText)
+		if !isEncrypted || got != params {
+			t.Errorf("ParseEncryptedConfItem() got = %v, expected %v", got, params)
+		}
+
+		plainText := DecryptIfEncryptedConfItem(cipherText, "pass")
+		if plainText != "abc#$%^&*d" {
+			t.Errorf("Decrypted text is not the same as original content")
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package secure
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// 脱敏类型，用于 Mask 和结构体标签 `mask:"phone"`
+const (
+	MaskKindPhone    = "phone"
+	MaskKindIdCard   = "idcard"
+	MaskKindEmail    = "email"
+	MaskKindBankCard = "bankcard"
+	MaskKindName     = "name"
+	MaskKindAll      = "all"
+)
+
+// 结构体嵌套的最大深度，防止指针循环引用导致无限递归
+const maxMaskDepth = 32
+
+var maskers = struct {
+	funcs map[string]func(string) string
+	mux   sync.RWMutex
+}{funcs: map[string]func(string) string{
+	MaskKindPhone:    MaskPhone,
+	MaskKindIdCard:   MaskIdCard,
+	MaskKindEmail:    MaskEmail,
+	MaskKindBankCard: MaskBankCard,
+	MaskKindName:     MaskName,
+	MaskKindAll:      MaskAll,
+}}
+
+// RegisterMasker 注册自定义脱敏类型，可覆盖内置类型
+func RegisterMasker(kind string, fn func(string) string) {
+	maskers.mux.Lock()
+	defer maskers.mux.Unlock()
+
+	maskers.funcs[kind] = fn
+}
+
+// Mask 按脱敏类型处理字符串，未知类型全部脱敏，宁可多遮也不能泄露
+func Mask(kind string, value string) string {
+	maskers.mux.RLock()
+	fn, has := maskers.funcs[kind]
+	maskers.mux.RUnlock()
+	if !has {
+		return MaskAll(value)
+	}
+
+	return fn(value)
+}
+
+// 保留前 keepStart 个和后 keepEnd 个字符，中间用 * 替换；长度不足时全部替换
+func maskMiddle(value string, keepStart int, keepEnd int) string {
+	runes := []rune(value)
+	if len(runes) <= keepStart+keepEnd {
+		return strings.Repeat("*", len(runes))
+	}
+
+	return string(runes[:keepStart]) + strings.Repeat("*", len(runes)-keepStart-keepEnd) + string(runes[len(runes)-keepEnd:])
+}
+
+// MaskPhone 手机号脱敏，13812345678 -> 138****5678
+func MaskPhone(value string) string {
+	return maskMiddle(value, 3, 4)
+}
+
+// MaskIdCard 身份证号脱敏，110101199003071234 -> 110***********1234
+func MaskIdCard(value string) string {
+	return maskMiddle(value, 3, 4)
+}
+
+// MaskEmail 邮箱脱敏，zhangsan@example.com -> z***@example.com
+func MaskEmail(value string) string {
+	at := strings.LastIndex(value, "@")
+	if at <= 0 {
+		return maskMiddle(value, 1, 0)
+	}
+
+	local := []rune(value[:at])
+	return string(local[0]) + "***" + value[at:]
+}
+
+// MaskBankCard 银行卡号脱敏，6222021234567890123 -> 6222***********0123
+func MaskBankCard(value string) string {
+	return maskMiddle(value, 4, 4)
+}
+
+// MaskName 姓名脱敏，张三丰 -> 张**
+func MaskName(value string) string {
+	return maskMiddle(value, 1, 0)
+}
+
+// MaskAll 全部替换为 *
+func MaskAll(value string) string {
+	return strings.Repeat("*", len([]rune(value)))
+}
+
+// MaskStruct 返回 v 的脱敏副本，不修改 v 本身
+//
+// 按结构体字段的 `mask` 标签脱敏字符串字段，支持嵌套结构体、指针、切片、数组和 map，
+// 标签作用于字段本身或其中的字符串元素，例如：
+//
+//	type User struct {
+//		Name   string   `json:"name" mask:"name"`
+//		Phone  string   `json:"phone" mask:"phone"`
+//		Emails []string `json:"emails" mask:"email"`
+//	}
+//
+// 非导出字段原样复制，没有需要脱敏的内容时原样返回 v，指针不会被复制
+func MaskStruct(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+
+	out, _ := maskValue(reflect.ValueOf(v), "", 0)
+	return out.Interface()
+}
+
+// 类型是否可能包含需要脱敏的内容：有 `mask` 标签的导出字段，或者导出的接口类型字段
+var maskableTypes sync.Map
+
+func maskable(t reflect.Type) bool {
+	if result, ok := maskableTypes.Load(t); ok {
+		return result.(bool)
+	}
+
+	// 只缓存最外层的结果，递归中遇到正在计算的类型时按 false 处理
+	result := typeMaskable(t, make(map[reflect.Type]bool))
+	maskableTypes.Store(t, result)
+	return result
+}
+
+func typeMaskable(t reflect.Type, visiting map[reflect.Type]bool) bool {
+	if visiting[t] {
+		return false
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	switch t.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return typeMaskable(t.Elem(), visiting)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			if field.Tag.Get("mask") != "" || typeMaskable(field.Type, visiting) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// maskValue 返回脱敏后的值和是否有修改，没有修改时返回 v 本身，有修改时只复制修改路径上的值
+func maskValue(v reflect.Value, kind string, depth int) (reflect.Value, bool) {
+	if depth > maxMaskDepth || kind == "" && !maskable(v.Type()) {
+		return v, false
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		if kind == "" {
+			return v, false
+		}
+		masked := Mask(kind, v.String())
+		if masked == v.String() {
+			return v, false
+		}
+		out := reflect.New(v.Type()).Elem()
+		out.SetString(masked)
+		return out, true
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v, false
+		}
+		elem, changed := maskValue(v.Elem(), kind, depth+1)
+		if !changed {
+			return v, false
+		}
+		out := reflect.New(v.Type().Elem())
+		out.Elem().Set(elem)
+		return out, true
+	case reflect.Interface:
+		if v.IsNil() {
+			return v, false
+		}
+		elem, changed := maskValue(v.Elem(), kind, depth+1)
+		if !changed {
+			return v, false
+		}
+		out := reflect.New(v.Type()).Elem()
+		out.Set(elem)
+		return out, true
+	case reflect.Struct:
+		var out reflect.Value
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			masked, changed := maskValue(v.Field(i), field.Tag.Get("mask"), depth+1)
+			if !changed {
+				continue
+			}
+			if !out.IsValid() {
+				out = reflect.New(t).Elem()
+				out.Set(v)
+			}
+			out.Field(i).Set(masked)
+		}
+		return maskResult(v, out)
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return v, false
+		}
+		var out reflect.Value
+		for i := 0; i < v.Len(); i++ {
+			masked, changed := maskValue(v.Index(i), kind, depth+1)
+			if !changed {
+				continue
+			}
+			if !out.IsValid() {
+				if v.Kind() == reflect.Slice {
+					out = reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+					reflect.Copy(out, v)
+				} else {
+					out = reflect.New(v.Type()).Elem()
+					out.Set(v)
+				}
+			}
+			out.Index(i).Set(masked)
+		}
+		return maskResult(v, out)
+	case reflect.Map:
+		if v.IsNil() {
+			return v, false
+		}
+		var out reflect.Value
+		iter := v.MapRange()
+		for iter.Next() {
+			masked, changed := maskValue(iter.Value(), kind, depth+1)
+			if !changed {
+				continue
+			}
+			if !out.IsValid() {
+				out = reflect.MakeMapWithSize(v.Type(), v.Len())
+				all := v.MapRange()
+				for all.Next() {
+					out.SetMapIndex(all.Key(), all.Value())
+				}
+			}
+			out.SetMapIndex(iter.Key(), masked)
+		}
+		return maskResult(v, out)
+	}
+
+	return v, false
+}
+
+// maskResult out 无效时表示没有修改
+func maskResult(v reflect.Value, out reflect.Value) (reflect.Value, bool) {
+	if !out.IsValid() {
+		return v, false
+	}
+	return out, true
+}
+
+// MaskFields 返回日志字段的脱敏副本：
+// 字段名已通过 RegisterMaskField 注册的按对应类型脱敏，结构体类型的值按 `mask` 标签脱敏，
+// 其他值原样保留，例如 error 指针仍然可以通过 errors.Is 判断
+func MaskFields(fields map[string]interface{}) map[string]interface{} {
+	if fields == nil {
+		return nil
+	}
+
+	maskFields.mux.RLock()
+	defer maskFields.mux.RUnlock()
+
+	result := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		if v == nil {
+			result[k] = v
+			continue
+		}
+		masked, _ := maskValue(reflect.ValueOf(v), maskFields.kinds[k], 0)
+		result[k] = masked.Interface()
+	}
+	return result
+}
+
+var maskFields = struct {
+	kinds map[string]string
+	mux   sync.RWMutex
+}{kinds: make(map[string]string)}
+
+// RegisterMaskField 注册需要脱敏的日志字段名，例如 RegisterMaskField("phone", MaskKindPhone)
+func RegisterMaskField(name string, kind string) {
+	maskFields.mux.Lock()
+	defer maskFields.mux.Unlock()
+
+	maskFields.kinds[name] = kind
+}
@@ -0,0 +1,113 @@
+package secure
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMask(t *testing.T) {
+	tests := []struct {
+		kind     string
+		input    string
+		expected string
+	}{
+		{MaskKindPhone, "13812345678", "138****5678"},
+		{MaskKindIdCard, "110101199003071234", "110***********1234"},
+		{MaskKindEmail, "zhangsan@example.com", "z***@example.com"},
+		{MaskKindBankCard, "6222021234567890123", "6222***********0123"},
+		{MaskKindName, "张三丰", "张**"},
+		{MaskKindPhone, "123", "***"},
+		{"unknown", "secret", "******"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			if got := Mask(tt.kind, tt.input); got != tt.expected {
+				t.Errorf("Mask() got = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+type maskAddress struct {
+	Phone string `mask:"phone"`
+}
+
+type maskUser struct {
+	Name      string            `mask:"name"`
+	Emails    []string          `mask:"email"`
+	Address   *maskAddress      `json:"address"`
+	Extra     map[string]string `mask:"all"`
+	Nickname  string
+	secretKey string
+}
+
+func TestMaskStruct(t *testing.T) {
+	user := maskUser{
+		Name:      "张三",
+		Emails:    []string{"zhangsan@example.com"},
+		Address:   &maskAddress{Phone: "13812345678"},
+		Extra:     map[string]string{"card": "1234"},
+		Nickname:  "san",
+		secretKey: "key",
+	}
+
+	masked := MaskStruct(&user).(*maskUser)
+	if masked.Name != "张*" || masked.Emails[0] != "z***@example.com" || masked.Address.Phone != "138****5678" ||
+		masked.Extra["card"] != "****" || masked.Nickname != "san" || masked.secretKey != "key" {
+		t.Errorf("MaskStruct() got = %+v", masked)
+	}
+
+	// 原值不能被修改
+	if user.Name != "张三" || user.Emails[0] != "zhangsan@example.com" || user.Address.Phone != "13812345678" {
+		t.Errorf("MaskStruct() modified original value %+v", user)
+	}
+}
+
+func TestMaskFields(t *testing.T) {
+	RegisterMaskField("phone", MaskKindPhone)
+
+	fields := MaskFields(map[string]interface{}{
+		"phone":   "13812345678",
+		"address": maskAddress{Phone: "13812345678"},
+		"sid":     "4020190409104630000101",
+	})
+
+	if fields["phone"] != "138****5678" || fields["address"].(maskAddress).Phone != "138****5678" ||
+		fields["sid"] != "4020190409104630000101" {
+		t.Errorf("MaskFields() got = %v", fields)
+	}
+}
+
+type maskError struct {
+	Op  string
+	Err error
+}
+
+func (e *maskError) Error() string { return e.Op + ": " + e.Err.Error() }
+
+func (e *maskError) Unwrap() error { return e.Err }
+
+func TestMaskFieldsKeepIdentity(t *testing.T) {
+	errNotFound := errors.New("not found")
+	wrapped := &maskError{Op: "get", Err: errNotFound}
+	user := &maskUser{Nickname: "san"}
+	fields := MaskFields(map[string]interface{}{
+		"error":   errNotFound,
+		"wrapped": wrapped,
+		"user":    user,
+		"data":    map[string]interface{}{"error": errNotFound},
+	})
+
+	// 不需要脱敏的值原样保留
+	if fields["error"] != errNotFound || fields["wrapped"] != wrapped || fields["user"] != user ||
+		!errors.Is(fields["wrapped"].(error), errNotFound) || fields["data"].(map[string]interface{})["error"] != errNotFound {
+		t.Errorf("MaskFields() got = %v", fields)
+	}
+
+	// 有需要脱敏的内容时才复制
+	user.Name = "张三"
+	if masked := MaskFields(map[string]interface{}{"user": user})["user"].(*maskUser); masked == user || masked.Name != "张*" {
+		t.Errorf("MaskFields() got = %+v", masked)
+	}
+}
@@ -0,0 +1,181 @@
+package secure
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PasswordBcrypt 使用 bcrypt 算法存储密码
+const PasswordBcrypt = "bcrypt"
+
+var (
+	ErrInvalidPasswordHash = errors.New("密码哈希格式错误")
+	ErrIncompatibleVersion = errors.New("不兼容的 argon2 版本")
+)
+
+// PasswordParams 密码哈希参数
+type PasswordParams struct {
+	Algorithm string // KDFArgon2id 或 PasswordBcrypt
+
+	// argon2id 参数
+	Time       uint32 // 迭代次数
+	Memory     uint32 // 内存开销，单位 KiB
+	Threads    uint8  // 并行线程数
+	SaltLength uint32 // 盐值长度，字节
+	KeyLength  uint32 // 哈希长度，字节
+
+	// bcrypt 参数
+	Cost int
+}
+
+// DefaultPasswordParams 默认密码哈希参数，参考 OWASP 对 argon2id 的推荐配置
+var DefaultPasswordParams = PasswordParams{
+	Algorithm:  KDFArgon2id,
+	Time:       2,
+	Memory:     19 * 1024,
+	Threads:    1,
+	SaltLength: 16,
+	KeyLength:  32,
+}
+
+// HashPassword 使用默认参数计算密码哈希，返回 PHC 格式字符串，例如：
+// $argon2id$v=19$m=19456,t=2,p=1$c2FsdA$aGFzaA
+func HashPassword(password string) (string, error) {
+	return HashPasswordWithParams(password, DefaultPasswordParams)
+}
+
+// HashPasswordWithParams 使用指定参数计算密码哈希
+// bcrypt 返回其标准的 $2a$cost$... 格式
+func HashPasswordWithParams(password string, params PasswordParams) (string, error) {
+	switch params.Algorithm {
+	case KDFArgon2id:
+		if params.Time == 0 || params.Threads == 0 || params.SaltLength == 0 || params.KeyLength == 0 {
+			return "", errors.New("argon2id 参数 time、threads、saltLength、keyLength 必须大于 0")
+		}
+
+		salt := make([]byte, params.SaltLength)
+		if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+			return "", err
+		}
+
+		hash := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, params.KeyLength)
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+			argon2.Version, params.Memory, params.Time, params.Threads,
+			base64.RawStdEncoding.EncodeToString(salt),
+			base64.RawStdEncoding.EncodeToString(hash)), nil
+	case PasswordBcrypt:
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), params.Cost)
+		if err != nil {
+			return "", err
+		}
+		return string(hash), nil
+	}
+
+	return "", fmt.Errorf("不支持的密码哈希算法 %q", params.Algorithm)
+}
+
+// VerifyPassword 校验密码是否与哈希匹配，比较过程是常量时间的
+// 密码不匹配时返回 false, nil；哈希格式错误时返回 error
+func VerifyPassword(password string, encoded string) (bool, error) {
+	if isBcryptHash(encoded) {
+		err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
+	params, salt, hash, err := decodeArgon2idHash(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	other := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, params.KeyLength)
+	return subtle.ConstantTimeCompare(hash, other) == 1, nil
+}
+
+// NeedsRehash 判断哈希是否使用了与默认参数不同的算法或参数，
+// 通常在登录校验成功后调用，需要时使用新参数重新计算并保存
+func NeedsRehash(encoded string) bool {
+	return NeedsRehashWithParams(encoded, DefaultPasswordParams)
+}
+
+// NeedsRehashWithParams 判断哈希是否使用了与 params 不同的算法或参数，
+// 无法解析的哈希也视为需要重新计算
+func NeedsRehashWithParams(encoded string, params PasswordParams) bool {
+	if isBcryptHash(encoded) {
+		if params.Algorithm != PasswordBcrypt {
+			return true
+		}
+		cost, err := bcrypt.Cost([]byte(encoded))
+		return err != nil || cost != params.Cost
+	}
+
+	if params.Algorithm != KDFArgon2id {
+		return true
+	}
+
+	current, salt, _, err := decodeArgon2idHash(encoded)
+	if err != nil {
+		return true
+	}
+
+	return current.Time != params.Time ||
+		current.Memory != params.Memory ||
+		current.Threads != params.Threads ||
+		current.KeyLength != params.KeyLength ||
+		uint32(len(salt)) != params.SaltLength
+}
+
+func isBcryptHash(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+// 解析 $argon2id$v=19$m=19456,t=2,p=1$salt$hash 格式
+func decodeArgon2idHash(encoded string) (PasswordParams, []byte, []byte, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[0] != "" || parts[1] != KDFArgon2id {
+		return PasswordParams{}, nil, nil, ErrInvalidPasswordHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return PasswordParams{}, nil, nil, ErrInvalidPasswordHash
+	}
+	if version != argon2.Version {
+		return PasswordParams{}, nil, nil, ErrIncompatibleVersion
+	}
+
+	params := PasswordParams{Algorithm: KDFArgon2id}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Time, &params.Threads); err != nil {
+		return PasswordParams{}, nil, nil, ErrInvalidPasswordHash
+	}
+	if params.Time == 0 || params.Threads == 0 {
+		return PasswordParams{}, nil, nil, ErrInvalidPasswordHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return PasswordParams{}, nil, nil, ErrInvalidPasswordHash
+	}
+	params.SaltLength = uint32(len(salt))
+
+	hash, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(hash) == 0 {
+		return PasswordParams{}, nil, nil, ErrInvalidPasswordHash
+	}
+	params.KeyLength = uint32(len(hash))
+
+	return params, salt, hash, nil
+}
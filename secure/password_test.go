@@ -0,0 +1,57 @@
+package secure
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPassword(t *testing.T) {
+	bcryptParams := PasswordParams{Algorithm: PasswordBcrypt, Cost: 4}
+	for _, params := range []PasswordParams{DefaultPasswordParams, bcryptParams} {
+		t.Run(params.Algorithm, func(t *testing.T) {
+			hash, err := HashPasswordWithParams("p@ssw0rd", params)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if ok, err := VerifyPassword("p@ssw0rd", hash); !ok || err != nil {
+				t.Errorf("VerifyPassword() got = %v, %v, expected true", ok, err)
+			}
+			if ok, err := VerifyPassword("wrong", hash); ok || err != nil {
+				t.Errorf("VerifyPassword() got = %v, %v, expected false", ok, err)
+			}
+			if NeedsRehashWithParams(hash, params) {
+				t.Errorf("NeedsRehashWithParams() should be false for same params")
+			}
+		})
+	}
+}
+
+func TestPasswordPHCFormat(t *testing.T) {
+	hash, _ := HashPassword("p@ssw0rd")
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=19456,t=2,p=1$") {
+		t.Errorf("HashPassword() got = %v", hash)
+	}
+
+	if _, err := VerifyPassword("p@ssw0rd", "$argon2id$v=19$m=19456$abc$def"); err != ErrInvalidPasswordHash {
+		t.Errorf("VerifyPassword() err = %v, expected %v", err, ErrInvalidPasswordHash)
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	weak := DefaultPasswordParams
+	weak.Time = 1
+	hash, _ := HashPasswordWithParams("p@ssw0rd", weak)
+	if !NeedsRehash(hash) {
+		t.Errorf("NeedsRehash() should be true for weaker params")
+	}
+
+	bcryptHash, _ := HashPasswordWithParams("p@ssw0rd", PasswordParams{Algorithm: PasswordBcrypt, Cost: 4})
+	if !NeedsRehash(bcryptHash) {
+		t.Errorf("NeedsRehash() should be true for different algorithm")
+	}
+
+	if !NeedsRehash("md5:abcdef") {
+		t.Errorf("NeedsRehash() should be true for unknown format")
+	}
+}
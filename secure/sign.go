@@ -0,0 +1,274 @@
+package secure
+
+import (
+	"bytes"
+	"container/heap"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 请求签名使用的 Header
+const (
+	HeaderSignKeyId     = "X-Haoxin-Key-Id"
+	HeaderSignTimestamp = "X-Haoxin-Timestamp"
+	HeaderSignNonce     = "X-Haoxin-Nonce"
+	HeaderSignature     = "X-Haoxin-Signature"
+)
+
+const (
+	DefaultSignMaxSkew     = 5 * time.Minute // 允许的客户端与服务端时间偏差
+	DefaultSignMaxBodySize = 10 << 20        // 校验签名时读取的最大请求 body，10 MiB
+)
+
+var (
+	ErrSignatureMissing = errors.New("请求未签名")
+	ErrSignatureExpired = errors.New("请求签名已过期")
+	ErrSignatureInvalid = errors.New("请求签名错误")
+	ErrSignKeyUnknown   = errors.New("未知的签名密钥")
+	ErrNonceReplayed    = errors.New("重复的请求")
+	ErrSignBodyTooLarge = errors.New("请求 body 超过签名校验允许的大小")
+)
+
+// DefaultSignedHeaders 默认参与签名的 Header，即服务之间传递的 sid、tid、userid、machineid，防止调用方身份被篡改，
+// Signer 和 Verifier 可以通过 Headers 修改
+var DefaultSignedHeaders = []string{"sid", "tid", "userid", "machineid"}
+
+// StringToSign 返回待签名的字符串，各部分以换行分隔：
+// method, path, query, sha256(body), timestamp, nonce, headers。
+// headers 为参与签名的 Header 按顺序拼接的 "name:value"，以换行分隔
+func StringToSign(method string, path string, query string, bodyHash string, timestamp string, nonce string, headers string) string {
+	return strings.Join([]string{strings.ToUpper(method), path, query, bodyHash, timestamp, nonce, headers}, "\n")
+}
+
+func signedHeaders(header http.Header, names []string) string {
+	if names == nil {
+		names = DefaultSignedHeaders
+	}
+	lines := make([]string, 0, len(names))
+	for _, name := range names {
+		lines = append(lines, strings.ToLower(name)+":"+header.Get(name))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// 计算请求的签名，会读取并还原 request.Body，maxBodySize 大于 0 时 body 超过该大小返回 ErrSignBodyTooLarge
+func requestSignature(request *http.Request, key []byte, timestamp string, nonce string, headers []string, maxBodySize int64) (string, error) {
+	var body []byte
+	if request.Body != nil && request.Body != http.NoBody {
+		if maxBodySize > 0 && request.ContentLength > maxBodySize {
+			return "", ErrSignBodyTooLarge
+		}
+
+		var reader io.Reader = request.Body
+		if maxBodySize > 0 {
+			reader = io.LimitReader(request.Body, maxBodySize+1)
+		}
+		var err error
+		body, err = ioutil.ReadAll(reader)
+		request.Body.Close()
+		if err != nil {
+			return "", err
+		}
+		if maxBodySize > 0 && int64(len(body)) > maxBodySize {
+			return "", ErrSignBodyTooLarge
+		}
+		request.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	bodyHash := sha256.Sum256(body)
+
+	path := request.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	data := StringToSign(request.Method, path, request.URL.Query().Encode(), hex.EncodeToString(bodyHash[:]), timestamp, nonce, signedHeaders(request.Header, headers))
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// Signer 使用 HMAC-SHA256 为服务间调用的请求签名
+type Signer struct {
+	KeyId   string
+	Key     []byte
+	Headers []string         // 参与签名的 Header，为 nil 时使用 DefaultSignedHeaders，需要与 Verifier 一致
+	Now     func() time.Time // 用于测试，默认 time.Now
+}
+
+func NewSigner(keyId string, key []byte) *Signer {
+	return &Signer{KeyId: keyId, Key: key, Now: time.Now}
+}
+
+// Sign 为请求计算签名，并设置签名相关的 Header，参与签名的 Header 需要在签名前设置
+func (s *Signer) Sign(request *http.Request) error {
+	nonce := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+
+	now := time.Now
+	if s.Now != nil {
+		now = s.Now
+	}
+
+	timestamp := strconv.FormatInt(now().Unix(), 10)
+	nonceText := hex.EncodeToString(nonce)
+	signature, err := requestSignature(request, s.Key, timestamp, nonceText, s.Headers, 0)
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set(HeaderSignKeyId, s.KeyId)
+	request.Header.Set(HeaderSignTimestamp, timestamp)
+	request.Header.Set(HeaderSignNonce, nonceText)
+	request.Header.Set(HeaderSignature, signature)
+	return nil
+}
+
+// NonceStore 记录已使用过的 nonce，用于拒绝重放请求
+type NonceStore interface {
+	// CheckAndStore 当 nonce 未使用过时记录并返回 true，已使用过返回 false
+	CheckAndStore(nonce string, expiration time.Duration) (bool, error)
+}
+
+// MemoryNonceStore 基于内存的 NonceStore，仅适用于单实例部署
+type MemoryNonceStore struct {
+	nonces  map[string]time.Time
+	expires nonceHeap // 按过期时间排序，只清理已过期的部分
+	mux     sync.Mutex
+	now     func() time.Time
+}
+
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{nonces: make(map[string]time.Time), now: time.Now}
+}
+
+func (m *MemoryNonceStore) CheckAndStore(nonce string, expiration time.Duration) (bool, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	now := m.now()
+	for len(m.expires) > 0 && !now.Before(m.expires[0].expireAt) {
+		e := heap.Pop(&m.expires).(nonceExpire)
+		// 同一个 nonce 过期后重新记录时，只删除最新的记录
+		if expireAt, has := m.nonces[e.nonce]; has && expireAt.Equal(e.expireAt) {
+			delete(m.nonces, e.nonce)
+		}
+	}
+
+	if expireAt, has := m.nonces[nonce]; has && now.Before(expireAt) {
+		return false, nil
+	}
+
+	expireAt := now.Add(expiration)
+	m.nonces[nonce] = expireAt
+	heap.Push(&m.expires, nonceExpire{nonce: nonce, expireAt: expireAt})
+	return true, nil
+}
+
+type nonceExpire struct {
+	nonce    string
+	expireAt time.Time
+}
+
+type nonceHeap []nonceExpire
+
+func (h nonceHeap) Len() int           { return len(h) }
+func (h nonceHeap) Less(i, j int) bool { return h[i].expireAt.Before(h[j].expireAt) }
+func (h nonceHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *nonceHeap) Push(x interface{}) {
+	*h = append(*h, x.(nonceExpire))
+}
+
+func (h *nonceHeap) Pop() interface{} {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
+
+// Verifier 校验请求签名，Keys 为 keyId 到密钥的映射，轮换密钥时可同时保留新旧密钥
+type Verifier struct {
+	Keys        map[string][]byte
+	MaxSkew     time.Duration
+	MaxBodySize int64    // 校验签名时读取的最大请求 body，默认 DefaultSignMaxBodySize
+	Headers     []string // 参与签名的 Header，为 nil 时使用 DefaultSignedHeaders，需要与 Signer 一致
+	Nonces      NonceStore
+	Now         func() time.Time // 用于测试，默认 time.Now
+}
+
+func NewVerifier(keys map[string][]byte, nonces NonceStore) *Verifier {
+	return &Verifier{Keys: keys, MaxSkew: DefaultSignMaxSkew, MaxBodySize: DefaultSignMaxBodySize, Nonces: nonces, Now: time.Now}
+}
+
+// Verify 校验请求签名、时间戳和 nonce，会读取并还原 request.Body，body 超过 MaxBodySize 时返回 ErrSignBodyTooLarge。
+// 应在修改参与签名的 Header 的中间件（如补充 sid 的中间件）之前校验
+func (v *Verifier) Verify(request *http.Request) error {
+	keyId := request.Header.Get(HeaderSignKeyId)
+	timestamp := request.Header.Get(HeaderSignTimestamp)
+	nonce := request.Header.Get(HeaderSignNonce)
+	signature := request.Header.Get(HeaderSignature)
+	if timestamp == "" || nonce == "" || signature == "" {
+		return ErrSignatureMissing
+	}
+
+	key, has := v.Keys[keyId]
+	if !has {
+		return ErrSignKeyUnknown
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrSignatureInvalid
+	}
+
+	now := time.Now
+	if v.Now != nil {
+		now = v.Now
+	}
+	maxSkew := v.MaxSkew
+	if maxSkew <= 0 {
+		maxSkew = DefaultSignMaxSkew
+	}
+	skew := now().Sub(time.Unix(ts, 0))
+	if skew > maxSkew || skew < -maxSkew {
+		return ErrSignatureExpired
+	}
+
+	maxBodySize := v.MaxBodySize
+	if maxBodySize <= 0 {
+		maxBodySize = DefaultSignMaxBodySize
+	}
+	expected, err := requestSignature(request, key, timestamp, nonce, v.Headers, maxBodySize)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrSignatureInvalid
+	}
+
+	// 签名通过后再记录 nonce，避免伪造请求占用 nonce
+	if v.Nonces != nil {
+		ok, err := v.Nonces.CheckAndStore(keyId+":"+nonce, 2*maxSkew)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrNonceReplayed
+		}
+	}
+
+	return nil
+}
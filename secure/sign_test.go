@@ -0,0 +1,184 @@
+package secure
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	key := []byte("secret")
+	signer := NewSigner("svc", key)
+	verifier := NewVerifier(map[string][]byte{"svc": key}, NewMemoryNonceStore())
+
+	request := httptest.NewRequest("POST", "/user/info?b=2&a=1", strings.NewReader(`{"userid":"1"}`))
+	if err := signer.Sign(request); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := verifier.Verify(request); err != nil {
+		t.Fatalf("Verify() err = %v", err)
+	}
+
+	// body 需要能被后续处理继续读取
+	body, _ := ioutil.ReadAll(request.Body)
+	if string(body) != `{"userid":"1"}` {
+		t.Errorf("request body got = %s", body)
+	}
+
+	replay := httptest.NewRequest("POST", "/user/info?b=2&a=1", strings.NewReader(`{"userid":"1"}`))
+	replay.Header = request.Header
+	if err := verifier.Verify(replay); err != ErrNonceReplayed {
+		t.Errorf("Verify() replay err = %v, expected %v", err, ErrNonceReplayed)
+	}
+}
+
+func TestSignCustomHeaders(t *testing.T) {
+	key := []byte("secret")
+	signer := NewSigner("svc", key)
+	signer.Headers = []string{"x-tenant"}
+	verifier := NewVerifier(map[string][]byte{"svc": key}, NewMemoryNonceStore())
+	verifier.Headers = []string{"x-tenant"}
+
+	for tenant, expected := range map[string]error{"t1": nil, "t2": ErrSignatureInvalid} {
+		request := httptest.NewRequest("GET", "/user/info", nil)
+		request.Header.Set("x-tenant", "t1")
+		signer.Sign(request)
+		request.Header.Set("x-tenant", tenant)
+		if err := verifier.Verify(request); err != expected {
+			t.Errorf("Verify(%s) err = %v, expected %v", tenant, err, expected)
+		}
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	key := []byte("secret")
+	signer := NewSigner("svc", key)
+
+	tests := []struct {
+		name     string
+		modify   func(v *Verifier, body *string, query *string)
+		header   func(header http.Header)
+		expected error
+	}{
+		{
+			name:     "tampered body",
+			modify:   func(v *Verifier, body *string, query *string) { *body = `{"userid":"2"}` },
+			expected: ErrSignatureInvalid,
+		},
+		{
+			name:     "tampered query",
+			modify:   func(v *Verifier, body *string, query *string) { *query = "a=2" },
+			expected: ErrSignatureInvalid,
+		},
+		{
+			name: "expired",
+			modify: func(v *Verifier, body *string, query *string) {
+				v.Now = func() time.Time { return time.Now().Add(time.Hour) }
+			},
+			expected: ErrSignatureExpired,
+		},
+		{
+			name:     "untampered",
+			expected: nil,
+		},
+		{
+			name:     "tampered userid",
+			header:   func(header http.Header) { header.Set("userid", "admin") },
+			expected: ErrSignatureInvalid,
+		},
+		{
+			name:     "tampered machineid",
+			header:   func(header http.Header) { header.Set("machineid", "m2") },
+			expected: ErrSignatureInvalid,
+		},
+		{
+			name:     "added sid",
+			header:   func(header http.Header) { header.Set("sid", "4020230102030405123456") },
+			expected: ErrSignatureInvalid,
+		},
+		{
+			name:     "removed tid",
+			header:   func(header http.Header) { header.Del("tid") },
+			expected: ErrSignatureInvalid,
+		},
+		{
+			name:     "unknown key",
+			modify:   func(v *Verifier, body *string, query *string) { v.Keys = map[string][]byte{"other": key} },
+			expected: ErrSignKeyUnknown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier := NewVerifier(map[string][]byte{"svc": key}, NewMemoryNonceStore())
+			body, query := `{"userid":"1"}`, "a=1"
+
+			signed := httptest.NewRequest("POST", "/user/info?"+query, strings.NewReader(body))
+			signed.Header.Set("userid", "1")
+			signed.Header.Set("machineid", "m1")
+			signed.Header.Set("tid", "t1")
+			signer.Sign(signed)
+
+			if tt.modify != nil {
+				tt.modify(verifier, &body, &query)
+			}
+			request := httptest.NewRequest("POST", "/user/info?"+query, strings.NewReader(body))
+			request.Header = signed.Header.Clone()
+			if tt.header != nil {
+				tt.header(request.Header)
+			}
+
+			if err := verifier.Verify(request); err != tt.expected {
+				t.Errorf("Verify() err = %v, expected %v", err, tt.expected)
+			}
+		})
+	}
+
+	// 超过大小限制的 body 不读取全部内容
+	verifier := NewVerifier(map[string][]byte{"svc": key}, NewMemoryNonceStore())
+	verifier.MaxBodySize = 8
+	for _, contentLength := range []int64{-1, 15} {
+		large := httptest.NewRequest("POST", "/user/info", strings.NewReader(`{"userid":"1"}`))
+		large.ContentLength = contentLength
+		signer.Sign(large)
+		if err := verifier.Verify(large); err != ErrSignBodyTooLarge {
+			t.Errorf("Verify() err = %v, expected %v", err, ErrSignBodyTooLarge)
+		}
+	}
+
+	request := httptest.NewRequest("GET", "/user/info", nil)
+	if err := NewVerifier(nil, nil).Verify(request); err != ErrSignatureMissing {
+		t.Errorf("Verify() err = %v, expected %v", err, ErrSignatureMissing)
+	}
+}
+
+func TestMemoryNonceStore(t *testing.T) {
+	store := NewMemoryNonceStore()
+	now := time.Unix(1700000000, 0)
+	store.now = func() time.Time { return now }
+
+	for i, nonce := range []string{"a", "b", "c"} {
+		if ok, _ := store.CheckAndStore(nonce, time.Duration(i+1)*time.Minute); !ok {
+			t.Fatalf("CheckAndStore(%s) should succeed", nonce)
+		}
+	}
+	if ok, _ := store.CheckAndStore("a", time.Minute); ok {
+		t.Errorf("CheckAndStore(a) should reject replay")
+	}
+
+	// 只清理已过期的 nonce
+	now = now.Add(2 * time.Minute)
+	if ok, _ := store.CheckAndStore("a", time.Minute); !ok {
+		t.Errorf("CheckAndStore(a) should succeed after expiration")
+	}
+	if ok, _ := store.CheckAndStore("c", time.Minute); ok {
+		t.Errorf("CheckAndStore(c) should reject replay")
+	}
+	if len(store.nonces) != 2 || len(store.expires) != 2 {
+		t.Errorf("nonces got = %v, expires = %v", store.nonces, store.expires)
+	}
+}